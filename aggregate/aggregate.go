@@ -0,0 +1,279 @@
+// SPDX-FileCopyrightText: 2025 Daniel Pfeifer <daniel@pfeifer-mail.de>
+// SPDX-License-Identifier: ISC
+
+package aggregate
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/chorse-dev/cdash-proxy/model"
+	"github.com/chorse-dev/cdash-proxy/profile"
+)
+
+// Aggregator collects the partial jobs that CTest submits one XML file at a
+// time and passes a single merged job on, either when Done.xml arrives or
+// when no further part was received within the idle timeout.
+//
+// A job that cannot be passed on is retried with an exponential backoff. If
+// the error of next has a method Retry, like *sink.PartialError, the retry
+// calls that method instead of next. Parts that arrive in the meantime are
+// merged and passed on after the job. Once the retries are exhausted, the job
+// is passed to Dead.
+type Aggregator struct {
+	MaxRetries int
+	Backoff    time.Duration
+
+	// Dead, if set, receives the jobs that could not be passed on.
+	Dead func(context.Context, *model.Job) error
+
+	// Profiles, if set, suppress the diagnostics of the merged jobs again,
+	// since merging changes the diagnostics of SARIF files.
+	Profiles profile.Registry
+
+	// Hold, if set, is called with the context of each part that Handle
+	// keeps. The function it returns, if any, is called once the job was
+	// passed on or passed to Dead, with nil, or with the error if the job was
+	// lost.
+	Hold func(ctx context.Context) func(error)
+
+	next    func(context.Context, *model.Job) error
+	timeout time.Duration
+
+	mu      sync.Mutex
+	pending map[string]*entry
+}
+
+type entry struct {
+	job      *model.Job
+	timer    *time.Timer
+	gen      int
+	attempts int
+
+	// retry passes the job on again after a partial failure.
+	retry func(context.Context, *model.Job) error
+
+	// later holds the parts that arrived while the job waits for a retry.
+	later *entry
+
+	releases []func(error)
+}
+
+// retrier is implemented by errors of next that can be retried for the
+// failed part only.
+type retrier interface {
+	Retry(ctx context.Context, job *model.Job) error
+}
+
+func New(next func(context.Context, *model.Job) error, timeout time.Duration) *Aggregator {
+	return &Aggregator{
+		MaxRetries: 5,
+		Backoff:    time.Second,
+		next:       next,
+		timeout:    timeout,
+		pending:    map[string]*entry{},
+	}
+}
+
+// Handle has the signature of web.HandlerFunc. It only fails if the job was
+// completed and could not be passed on even after all retries.
+func (a *Aggregator) Handle(ctx context.Context, job *model.Job) error {
+	a.mu.Lock()
+	e, found := a.pending[job.JobID]
+	if !found {
+		e = newEntry(job.JobID)
+		a.pending[job.JobID] = e
+	}
+
+	if e.attempts > 0 {
+		if e.later == nil {
+			e.later = newEntry(job.JobID)
+		}
+		Merge(e.later.job, job)
+		a.hold(ctx, e.later)
+		a.mu.Unlock()
+		return nil
+	}
+
+	Merge(e.job, job)
+	a.hold(ctx, e)
+	if e.timer != nil {
+		e.timer.Stop()
+	}
+
+	if e.job.Done {
+		delete(a.pending, job.JobID)
+		a.mu.Unlock()
+		return a.emit(ctx, e)
+	}
+
+	a.schedule(e, a.timeout)
+	a.mu.Unlock()
+	return nil
+}
+
+func newEntry(jobID string) *entry {
+	return &entry{job: &model.Job{JobID: jobID}}
+}
+
+func (a *Aggregator) hold(ctx context.Context, e *entry) {
+	if a.Hold == nil {
+		return
+	}
+	if release := a.Hold(ctx); release != nil {
+		e.releases = append(e.releases, release)
+	}
+}
+
+// release calls the release functions of the parts of the entry.
+func release(e *entry, err error) {
+	for _, r := range e.releases {
+		r(err)
+	}
+	e.releases = nil
+}
+
+// schedule emits the entry after the delay, unless it is changed before.
+// The caller must hold the lock.
+func (a *Aggregator) schedule(e *entry, delay time.Duration) {
+	e.gen++
+	gen := e.gen
+	e.timer = time.AfterFunc(delay, func() {
+		a.expire(e, gen)
+	})
+}
+
+func (a *Aggregator) expire(e *entry, gen int) {
+	a.mu.Lock()
+	if a.pending[e.job.JobID] != e || e.gen != gen {
+		a.mu.Unlock()
+		return
+	}
+	delete(a.pending, e.job.JobID)
+	a.mu.Unlock()
+
+	if err := a.emit(context.Background(), e); err != nil {
+		log.Printf("job %s: %v", e.job.JobID, err)
+	}
+}
+
+// emit passes the job of an entry that is no longer pending on. If that
+// fails, the entry becomes pending again for a retry and nil is returned.
+func (a *Aggregator) emit(ctx context.Context, e *entry) error {
+	err := a.pass(ctx, e)
+	if err == nil {
+		release(e, nil)
+		return a.promote(ctx, e)
+	}
+
+	e.attempts++
+	log.Printf("job %s: attempt %d: %v", e.job.JobID, e.attempts, err)
+	var r retrier
+	if errors.As(err, &r) {
+		e.retry = r.Retry
+	}
+	if e.attempts > a.MaxRetries {
+		err = a.bury(ctx, e, err)
+		return errors.Join(err, a.promote(ctx, e))
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if later, found := a.pending[e.job.JobID]; found {
+		later.timer.Stop()
+		e.later = joinEntries(e.later, later)
+	}
+	a.pending[e.job.JobID] = e
+	a.schedule(e, a.Backoff<<(e.attempts-1))
+	return nil
+}
+
+// promote makes the parts that arrived while the job of the entry was retried
+// pending, or emits them if they are done.
+func (a *Aggregator) promote(ctx context.Context, e *entry) error {
+	a.mu.Lock()
+	later := e.later
+	e.later = nil
+	if later == nil {
+		a.mu.Unlock()
+		return nil
+	}
+	if current, found := a.pending[later.job.JobID]; found {
+		current.timer.Stop()
+		later = joinEntries(later, current)
+	}
+
+	if later.job.Done {
+		delete(a.pending, later.job.JobID)
+		a.mu.Unlock()
+		return a.emit(ctx, later)
+	}
+
+	a.pending[later.job.JobID] = later
+	a.schedule(later, a.timeout)
+	a.mu.Unlock()
+	return nil
+}
+
+// joinEntries merges the entry b, whose parts arrived after those of a, into
+// a. Either may be nil.
+func joinEntries(a, b *entry) *entry {
+	if a == nil {
+		return b
+	}
+	if b != nil {
+		Merge(a.job, b.job)
+		a.releases = append(a.releases, b.releases...)
+	}
+	return a
+}
+
+func (a *Aggregator) pass(ctx context.Context, e *entry) error {
+	a.Profiles.Lookup(e.job.Project).SuppressDiagnostics(e.job)
+	if e.retry != nil {
+		return e.retry(ctx, e.job)
+	}
+	return a.next(ctx, e.job)
+}
+
+// bury passes a job that could not be passed on to Dead.
+func (a *Aggregator) bury(ctx context.Context, e *entry, err error) error {
+	if a.Dead == nil {
+		release(e, err)
+		return err
+	}
+	if deadErr := a.Dead(ctx, e.job); deadErr != nil {
+		err = errors.Join(err, deadErr)
+		release(e, err)
+		return err
+	}
+	release(e, nil)
+	return err
+}
+
+// Flush passes all pending jobs on, regardless of whether they are done.
+// Jobs that cannot be passed on are not retried but passed to Dead.
+func (a *Aggregator) Flush(ctx context.Context) error {
+	a.mu.Lock()
+	pending := a.pending
+	a.pending = map[string]*entry{}
+	a.mu.Unlock()
+
+	var errs []error
+	for _, e := range pending {
+		for ; e != nil; e = e.later {
+			if e.timer != nil {
+				e.timer.Stop()
+			}
+			if err := a.pass(ctx, e); err != nil {
+				errs = append(errs, a.bury(ctx, e, err))
+				continue
+			}
+			release(e, nil)
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,336 @@
+// SPDX-FileCopyrightText: 2025 Daniel Pfeifer <daniel@pfeifer-mail.de>
+// SPDX-License-Identifier: ISC
+
+package aggregate
+
+import (
+	"context"
+	"errors"
+	"maps"
+	"os"
+	"slices"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/chorse-dev/cdash-proxy/ctestxml"
+	"github.com/chorse-dev/cdash-proxy/model"
+	"github.com/chorse-dev/cdash-proxy/profile"
+	"github.com/chorse-dev/cdash-proxy/sarif"
+	"github.com/chorse-dev/cdash-proxy/suppress"
+)
+
+func parseFile(t *testing.T, name string) *model.Job {
+	f, err := os.Open("../ctestxml/testdata/" + name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	job, err := ctestxml.Parse(f, "Example")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return job
+}
+
+func TestDone(t *testing.T) {
+	var jobs []*model.Job
+	a := New(func(ctx context.Context, job *model.Job) error {
+		jobs = append(jobs, job)
+		return nil
+	}, time.Hour)
+
+	ctx := context.Background()
+	for _, name := range []string{"Configure.xml", "Done.xml"} {
+		if err := a.Handle(ctx, parseFile(t, name)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if len(jobs) != 1 {
+		t.Fatalf("expected one job, got %d", len(jobs))
+	}
+	if !jobs[0].Done || jobs[0].StartConfigureTime == nil || len(jobs[0].Commands) == 0 {
+		t.Errorf("job was not merged: %+v", jobs[0])
+	}
+}
+
+func TestTimeout(t *testing.T) {
+	emitted := make(chan *model.Job, 1)
+	a := New(func(ctx context.Context, job *model.Job) error {
+		emitted <- job
+		return nil
+	}, 10*time.Millisecond)
+
+	if err := a.Handle(context.Background(), parseFile(t, "Configure.xml")); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case job := <-emitted:
+		if job.Done {
+			t.Error("expected job not to be done")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("job was not emitted after idle timeout")
+	}
+}
+
+func TestRetry(t *testing.T) {
+	var attempts atomic.Int32
+	emitted := make(chan *model.Job, 1)
+	a := New(func(ctx context.Context, job *model.Job) error {
+		if attempts.Add(1) == 1 {
+			return errors.New("sink unavailable")
+		}
+		emitted <- job
+		return nil
+	}, time.Hour)
+	a.Backoff = time.Millisecond
+
+	ctx := context.Background()
+	for _, name := range []string{"Configure.xml", "Done.xml"} {
+		if err := a.Handle(ctx, parseFile(t, name)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	select {
+	case job := <-emitted:
+		if !job.Done || job.StartConfigureTime == nil {
+			t.Errorf("job was not merged: %+v", job)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("job was not retried")
+	}
+}
+
+func TestRetryLater(t *testing.T) {
+	var jobs []*model.Job
+	a := New(func(ctx context.Context, job *model.Job) error {
+		jobs = append(jobs, job)
+		if len(jobs) == 1 {
+			return errors.New("sink unavailable")
+		}
+		return nil
+	}, time.Hour)
+	a.Backoff = time.Hour
+
+	ctx := context.Background()
+	for _, name := range []string{"Configure.xml", "Done.xml", "Build.xml"} {
+		if err := a.Handle(ctx, parseFile(t, name)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := a.Flush(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	// The part that arrived during the retry is passed on after the job.
+	if len(jobs) != 3 || jobs[1] != jobs[0] {
+		t.Fatalf("expected the job to be retried, got %d jobs", len(jobs))
+	}
+	if jobs[1].StartBuildTime != nil || jobs[2].StartBuildTime == nil || jobs[2].StartConfigureTime != nil {
+		t.Error("expected the build to be passed on separately")
+	}
+}
+
+func TestDead(t *testing.T) {
+	var dead []*model.Job
+	a := New(func(ctx context.Context, job *model.Job) error {
+		return errors.New("sink unavailable")
+	}, time.Hour)
+	a.MaxRetries = 0
+	a.Dead = func(ctx context.Context, job *model.Job) error {
+		dead = append(dead, job)
+		return nil
+	}
+
+	ctx := context.Background()
+	if err := a.Handle(ctx, parseFile(t, "Configure.xml")); err != nil {
+		t.Fatal(err)
+	}
+	if err := a.Handle(ctx, parseFile(t, "Done.xml")); err == nil {
+		t.Error("expected error")
+	}
+
+	if len(dead) != 1 || !dead[0].Done || dead[0].StartConfigureTime == nil {
+		t.Errorf("expected the merged job to be dead, got %+v", dead)
+	}
+}
+
+type nameKey struct{}
+
+func TestHold(t *testing.T) {
+	fail := true
+	a := New(func(ctx context.Context, job *model.Job) error {
+		if fail {
+			return errors.New("sink unavailable")
+		}
+		return nil
+	}, time.Hour)
+	a.Backoff = time.Hour
+
+	released := map[string]error{}
+	a.Hold = func(ctx context.Context) func(error) {
+		name := ctx.Value(nameKey{}).(string)
+		return func(err error) {
+			released[name] = err
+		}
+	}
+
+	ctx := context.Background()
+	for _, name := range []string{"Configure.xml", "Done.xml"} {
+		if err := a.Handle(context.WithValue(ctx, nameKey{}, name), parseFile(t, name)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// The parts are held while the job waits for a retry.
+	if len(released) != 0 {
+		t.Fatalf("expected the parts to be held, got %v", released)
+	}
+
+	fail = false
+	if err := a.Flush(ctx); err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]error{"Configure.xml": nil, "Done.xml": nil}
+	if !maps.Equal(want, released) {
+		t.Errorf("expected the parts to be released, got %v", released)
+	}
+}
+
+func TestMergeDynamicAnalysis(t *testing.T) {
+	job := parseFile(t, "DynamicAnalysis.xml")
+	checked := len(job.Commands)
+	test := parseFile(t, "DynamicAnalysis-Test.xml")
+	count := len(test.Commands)
+	Merge(job, test)
+
+	if len(job.Commands) != count {
+		t.Fatalf("expected %d commands, got %d", count, len(job.Commands))
+	}
+	for _, cmd := range job.Commands[:checked] {
+		if cmd.Attributes["DA Checker"] != "Valgrind" || cmd.WorkingDirectory == "" {
+			t.Errorf("%s: commands were not merged", cmd.TestName)
+		}
+	}
+	if job.StartMemcheckTime == nil || job.StartTestTime == nil {
+		t.Error("expected both memcheck and test times")
+	}
+}
+
+func TestMergeSARIF(t *testing.T) {
+	for _, names := range [][2]string{
+		{"BuildIL.xml", "Upload-SARIF.xml"},
+		{"Upload-SARIF.xml", "BuildIL.xml"},
+	} {
+		build := parseFile(t, "BuildIL.xml")
+		job := &model.Job{}
+		Merge(job, parseFile(t, names[0]))
+		Merge(job, parseFile(t, names[1]))
+
+		if len(job.Commands) != len(build.Commands) {
+			t.Fatalf("%v: expected %d commands, got %d", names, len(build.Commands), len(job.Commands))
+		}
+		idx := slices.IndexFunc(job.Commands, func(cmd model.Command) bool {
+			return cmd.Source == "Hello/hello.c"
+		})
+		if idx == -1 {
+			t.Fatalf("%v: expected command for Hello/hello.c", names)
+		}
+		cmd := job.Commands[idx]
+		if cmd.Attributes[sarif.Attribute] == "" || cmd.Target == "" {
+			t.Errorf("%v: commands were not merged", names)
+		}
+		if len(cmd.Diagnostics) != 2 {
+			t.Fatalf("%v: expected 2 diagnostics, got %d", names, len(cmd.Diagnostics))
+		}
+		diag := cmd.Diagnostics[1]
+		if diag.FilePath != "Hello/hello.c" || diag.Line != 7 || diag.Column != 10 {
+			t.Errorf("%v: unexpected location %s:%d:%d", names, diag.FilePath, diag.Line, diag.Column)
+		}
+		if len(diag.Related) != 1 || diag.Related[0].FilePath != "Hello/hello.h" {
+			t.Errorf("%v: unexpected related locations %v", names, diag.Related)
+		}
+	}
+}
+
+func TestMergeSARIFFingerprint(t *testing.T) {
+	fingerprints := func(root string) []string {
+		upload := parseFile(t, "Upload-SARIF.xml")
+		for i := range upload.Commands {
+			cmd := &upload.Commands[i]
+			cmd.Source = strings.Replace(cmd.Source, "/home/daniel/Projects/", root, 1)
+			for j := range cmd.Diagnostics {
+				d := &cmd.Diagnostics[j]
+				d.FilePath = strings.Replace(d.FilePath, "/home/daniel/Projects/", root, 1)
+			}
+		}
+
+		job := parseFile(t, "BuildIL.xml")
+		Merge(job, upload)
+
+		var fps []string
+		for _, cmd := range job.Commands {
+			if cmd.Source != "Hello/hello.c" {
+				continue
+			}
+			for _, d := range cmd.Diagnostics {
+				fps = append(fps, d.Fingerprint)
+			}
+		}
+		return fps
+	}
+
+	home := fingerprints("/home/daniel/Projects/")
+	ci := fingerprints("/builds/")
+	if len(home) != 2 || !slices.Equal(home, ci) {
+		t.Errorf("fingerprints depend on the source directory: %v and %v", home, ci)
+	}
+}
+
+func TestMergeSARIFSuppress(t *testing.T) {
+	p := &profile.Profile{Suppress: []suppress.Rule{{File: "Hello/hello.c", Reason: "vendored"}}}
+	if err := p.Compile(); err != nil {
+		t.Fatal(err)
+	}
+
+	var job *model.Job
+	a := New(func(ctx context.Context, j *model.Job) error {
+		job = j
+		return nil
+	}, time.Hour)
+	a.Profiles = profile.Registry{"Example": p}
+
+	ctx := context.Background()
+	build := parseFile(t, "BuildIL.xml")
+	upload := parseFile(t, "Upload-SARIF.xml")
+	upload.JobID = build.JobID
+	for _, part := range []*model.Job{build, upload} {
+		if err := a.Handle(ctx, part); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := a.Flush(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	suppressed := 0
+	for _, cmd := range job.Commands {
+		for _, d := range cmd.Diagnostics {
+			if d.FilePath != "Hello/hello.c" {
+				continue
+			}
+			if d.SuppressedBy != "vendored" {
+				t.Errorf("diagnostic %s:%d was not suppressed", d.FilePath, d.Line)
+			}
+			suppressed++
+		}
+	}
+	if suppressed == 0 {
+		t.Error("expected diagnostics in Hello/hello.c")
+	}
+}
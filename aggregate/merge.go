@@ -0,0 +1,240 @@
+// SPDX-FileCopyrightText: 2025 Daniel Pfeifer <daniel@pfeifer-mail.de>
+// SPDX-License-Identifier: ISC
+
+package aggregate
+
+import (
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/chorse-dev/cdash-proxy/fingerprint"
+	"github.com/chorse-dev/cdash-proxy/model"
+	"github.com/chorse-dev/cdash-proxy/sarif"
+)
+
+// Merge combines the partial job src into dst. Both are expected to carry the
+// same JobID. Scalar fields of dst are only filled if they are still empty.
+func Merge(dst, src *model.Job) {
+	mergeString(&dst.Project, src.Project)
+	mergeString(&dst.BuildName, src.BuildName)
+	mergeString(&dst.BuildGroup, src.BuildGroup)
+	mergeString(&dst.ChangeID, src.ChangeID)
+	mergeString(&dst.PriorChangeID, src.PriorChangeID)
+	mergeString(&dst.Generator, src.Generator)
+
+	if dst.Host == nil {
+		dst.Host = src.Host
+	}
+
+	mergeTime(&dst.StartUpdateTime, src.StartUpdateTime)
+	mergeTime(&dst.EndUpdateTime, src.EndUpdateTime)
+	mergeTime(&dst.StartConfigureTime, src.StartConfigureTime)
+	mergeTime(&dst.EndConfigureTime, src.EndConfigureTime)
+	mergeTime(&dst.StartBuildTime, src.StartBuildTime)
+	mergeTime(&dst.EndBuildTime, src.EndBuildTime)
+	mergeTime(&dst.StartTestTime, src.StartTestTime)
+	mergeTime(&dst.EndTestTime, src.EndTestTime)
+	mergeTime(&dst.StartCoverageTime, src.StartCoverageTime)
+	mergeTime(&dst.EndCoverageTime, src.EndCoverageTime)
+	mergeTime(&dst.StartMemcheckTime, src.StartMemcheckTime)
+	mergeTime(&dst.EndMemcheckTime, src.EndMemcheckTime)
+
+	if len(dst.Commits) == 0 {
+		dst.Commits = src.Commits
+	}
+	for _, cmd := range src.Commands {
+		mergeCommands(dst, cmd)
+	}
+	for _, cov := range src.Coverage {
+		mergeCoverage(dst, cov)
+	}
+
+	dst.AttachedFiles = append(dst.AttachedFiles, src.AttachedFiles...)
+	dst.Done = dst.Done || src.Done
+	dst.ParserVersion = max(dst.ParserVersion, src.ParserVersion)
+	dst.Reprocessed = dst.Reprocessed || src.Reprocessed
+}
+
+// DynamicAnalysis.xml and DynamicAnalysis-Test.xml both describe the same
+// tests. The former carries the checker output, the latter the regular test
+// results. Both are merged into a single command.
+func mergeCommands(dst *model.Job, cmd model.Command) {
+	for i := range dst.Commands {
+		other := &dst.Commands[i]
+		if isSARIF(*other) != isSARIF(cmd) && sameAnalysis(*other, cmd) {
+			mergeSARIF(other, cmd)
+			return
+		}
+	}
+
+	if cmd.Role == "test" {
+		for i := range dst.Commands {
+			other := &dst.Commands[i]
+			if other.Role != "test" || other.TestName != cmd.TestName {
+				continue
+			}
+			if isMemcheck(*other) != isMemcheck(cmd) {
+				mergeCommand(other, cmd)
+				return
+			}
+		}
+	}
+	dst.Commands = append(dst.Commands, cmd)
+}
+
+func isMemcheck(cmd model.Command) bool {
+	_, found := cmd.Attributes["DA Checker"]
+	return found
+}
+
+func isSARIF(cmd model.Command) bool {
+	_, found := cmd.Attributes[sarif.Attribute]
+	return found
+}
+
+// sameAnalysis reports whether a command created from a SARIF file attached
+// to the job describes the other command. The configure step is identified by
+// its role, compile commands by their source.
+func sameAnalysis(a, b model.Command) bool {
+	if a.Role != b.Role {
+		return false
+	}
+	switch a.Role {
+	case "configure":
+		return true
+	case "compile":
+		return sameSource(a.Source, b.Source)
+	}
+	return false
+}
+
+// sameSource compares paths of which one may be relative to the source
+// directory.
+func sameSource(a, b string) bool {
+	if a == "" || b == "" {
+		return false
+	}
+	return a == b || strings.HasSuffix(a, "/"+b) || strings.HasSuffix(b, "/"+a)
+}
+
+// mergeSARIF merges a command and the command that was created from a SARIF
+// file for it. The diagnostics of the SARIF file take precedence. Absolute
+// paths in the SARIF file are made relative like the source of the command.
+// The fingerprints are computed again from the relative paths, so they do not
+// depend on the source directory, and suppressions have to be applied again.
+func mergeSARIF(dst *model.Command, src model.Command) {
+	analysis, cmd := src, *dst
+	if isSARIF(*dst) {
+		analysis, cmd = *dst, src
+	}
+
+	root, found := strings.CutSuffix(analysis.Source, cmd.Source)
+	relative := found && cmd.Source != analysis.Source && strings.HasSuffix(root, "/")
+	for i := range analysis.Diagnostics {
+		d := &analysis.Diagnostics[i]
+		if relative {
+			d.FilePath = relativeTo(root, d.FilePath)
+			for j := range d.Related {
+				d.Related[j].FilePath = relativeTo(root, d.Related[j].FilePath)
+			}
+		}
+		d.Fingerprint = fingerprint.Diagnostic(d, "")
+		d.Suppressed, d.SuppressedBy = false, ""
+	}
+
+	cmd.Diagnostics = analysis.Diagnostics
+	cmd.Attributes = mergeMap(cmd.Attributes, analysis.Attributes)
+	*dst = cmd
+}
+
+func relativeTo(root, file string) string {
+	if rel, found := strings.CutPrefix(file, root); found {
+		return rel
+	}
+	return file
+}
+
+func mergeCommand(dst *model.Command, src model.Command) {
+	mergeString(&dst.CommandLine, src.CommandLine)
+	mergeString(&dst.WorkingDirectory, src.WorkingDirectory)
+	mergeString(&dst.TestStatus, src.TestStatus)
+	mergeString(&dst.StdOut, src.StdOut)
+	mergeString(&dst.StdErr, src.StdErr)
+
+	if dst.Result == 0 {
+		dst.Result = src.Result
+	}
+	if dst.StartTime == nil {
+		dst.StartTime = src.StartTime
+	}
+	if dst.Duration == 0 {
+		dst.Duration = src.Duration
+	}
+	if len(dst.TargetLabels) == 0 {
+		dst.TargetLabels = src.TargetLabels
+	}
+
+	dst.Diagnostics = append(dst.Diagnostics, src.Diagnostics...)
+	dst.AttachedFiles = append(dst.AttachedFiles, src.AttachedFiles...)
+	dst.Attributes = mergeMap(dst.Attributes, src.Attributes)
+	dst.Measurements = mergeMap(dst.Measurements, src.Measurements)
+}
+
+// Coverage.xml carries the summary and CoverageLog.xml the line coverage of
+// the same files.
+func mergeCoverage(dst *model.Job, cov model.Coverage) {
+	idx := slices.IndexFunc(dst.Coverage, func(c model.Coverage) bool {
+		return c.FilePath == cov.FilePath
+	})
+	if idx == -1 {
+		dst.Coverage = append(dst.Coverage, cov)
+		return
+	}
+
+	other := &dst.Coverage[idx]
+	if len(other.Lines) == 0 {
+		other.Lines = cov.Lines
+	}
+	mergeInt(&other.LinesTested, cov.LinesTested)
+	mergeInt(&other.LinesUntested, cov.LinesUntested)
+	mergeInt(&other.BranchesTested, cov.BranchesTested)
+	mergeInt(&other.BranchesUntested, cov.BranchesUntested)
+	mergeInt(&other.FunctionsTested, cov.FunctionsTested)
+	mergeInt(&other.FunctionsUntested, cov.FunctionsUntested)
+	for _, label := range cov.Labels {
+		if !slices.Contains(other.Labels, label) {
+			other.Labels = append(other.Labels, label)
+		}
+	}
+}
+
+func mergeString(dst *string, src string) {
+	if *dst == "" {
+		*dst = src
+	}
+}
+
+func mergeTime(dst **time.Time, src *time.Time) {
+	if *dst == nil {
+		*dst = src
+	}
+}
+
+func mergeInt(dst **int, src *int) {
+	if *dst == nil {
+		*dst = src
+	}
+}
+
+func mergeMap[V any](dst, src map[string]V) map[string]V {
+	if dst == nil && len(src) != 0 {
+		dst = make(map[string]V, len(src))
+	}
+	for k, v := range src {
+		if _, found := dst[k]; !found {
+			dst[k] = v
+		}
+	}
+	return dst
+}
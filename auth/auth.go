@@ -0,0 +1,152 @@
+// SPDX-FileCopyrightText: 2025 Daniel Pfeifer <daniel@pfeifer-mail.de>
+// SPDX-License-Identifier: ISC
+
+package auth
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+)
+
+var (
+	ErrMissingToken   = errors.New("authentication required")
+	ErrInvalidToken   = errors.New("invalid token")
+	ErrUnknownProject = errors.New("unknown project")
+)
+
+// Token grants submission access to a set of projects, optionally restricted
+// to a set of sites. The project "*" matches any project. Tokens may be given
+// in clear text or as hex encoded SHA-256 hash.
+type Token struct {
+	Token    string   `json:"token,omitempty"`
+	SHA256   string   `json:"sha256,omitempty"`
+	Projects []string `json:"projects"`
+	Sites    []string `json:"sites,omitempty"`
+}
+
+// Tokens decides which submissions are accepted. Projects that appear in any
+// token require authentication; other projects are accepted anonymously,
+// unless RejectUnknown is set.
+type Tokens struct {
+	Tokens        []Token
+	RejectUnknown bool
+}
+
+// Load reads tokens from a JSON file containing an array of tokens.
+func Load(path string) (*Tokens, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var tokens []Token
+	if err := json.Unmarshal(data, &tokens); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	for i, t := range tokens {
+		if t.Token == "" && t.SHA256 == "" {
+			return nil, fmt.Errorf("%s: token %d has neither token nor sha256", path, i)
+		}
+		if len(t.Projects) == 0 {
+			return nil, fmt.Errorf("%s: token %d has no projects", path, i)
+		}
+	}
+
+	return &Tokens{Tokens: tokens}, nil
+}
+
+// BearerToken returns the token from the Authorization header of r.
+func BearerToken(r *http.Request) string {
+	scheme, token, found := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !found || !strings.EqualFold(scheme, "Bearer") {
+		return ""
+	}
+	return strings.TrimSpace(token)
+}
+
+// Known reports whether any token grants access to project.
+func (t *Tokens) Known(project string) bool {
+	for _, tok := range t.Tokens {
+		if slices.Contains(tok.Projects, project) {
+			return true
+		}
+	}
+	return false
+}
+
+// Authorize checks whether token may submit to project from site. An empty
+// site is not checked.
+func (t *Tokens) Authorize(token, project, site string) error {
+	if !t.Known(project) && !t.wildcard() {
+		if t.RejectUnknown {
+			return fmt.Errorf("%w %q", ErrUnknownProject, project)
+		}
+		return nil
+	}
+
+	if token == "" {
+		return ErrMissingToken
+	}
+
+	tok := t.find(token)
+	if tok == nil {
+		return ErrInvalidToken
+	}
+
+	if !slices.Contains(tok.Projects, project) && !slices.Contains(tok.Projects, "*") {
+		if !t.Known(project) && t.RejectUnknown {
+			return fmt.Errorf("%w %q", ErrUnknownProject, project)
+		}
+		return fmt.Errorf("token is not valid for project %q", project)
+	}
+
+	if site != "" && len(tok.Sites) != 0 && !slices.Contains(tok.Sites, site) {
+		return fmt.Errorf("token is not valid for site %q", site)
+	}
+
+	return nil
+}
+
+// A wildcard token makes every project require authentication.
+func (t *Tokens) wildcard() bool {
+	for _, tok := range t.Tokens {
+		if slices.Contains(tok.Projects, "*") {
+			return true
+		}
+	}
+	return false
+}
+
+func (t *Tokens) find(token string) *Token {
+	sum := sha256.Sum256([]byte(token))
+	hash := hex.EncodeToString(sum[:])
+	for i, tok := range t.Tokens {
+		if tok.Token != "" && subtle.ConstantTimeCompare([]byte(tok.Token), []byte(token)) == 1 {
+			return &t.Tokens[i]
+		}
+		if tok.SHA256 != "" && subtle.ConstantTimeCompare([]byte(strings.ToLower(tok.SHA256)), []byte(hash)) == 1 {
+			return &t.Tokens[i]
+		}
+	}
+	return nil
+}
+
+// RestrictsSites reports whether token is only valid for some sites.
+func (t *Tokens) RestrictsSites(token string) bool {
+	tok := t.find(token)
+	return tok != nil && len(tok.Sites) != 0
+}
+
+// Valid reports whether token is valid for any project.
+func (t *Tokens) Valid(token string) bool {
+	return t.find(token) != nil
+}
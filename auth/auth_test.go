@@ -0,0 +1,67 @@
+// SPDX-FileCopyrightText: 2025 Daniel Pfeifer <daniel@pfeifer-mail.de>
+// SPDX-License-Identifier: ISC
+
+package auth
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const tokensJSON = `[
+	{"token": "secret", "projects": ["Example"]},
+	{"sha256": "6ee0eb490ff832101cf82a3d387c35f29e4230be786978f7acf9e811febf6723", "projects": ["Other"], "sites": ["ci"]}
+]`
+
+func loadTokens(t *testing.T) *Tokens {
+	path := filepath.Join(t.TempDir(), "tokens.json")
+	if err := os.WriteFile(path, []byte(tokensJSON), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	tokens, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tokens
+}
+
+func TestAuthorize(t *testing.T) {
+	tokens := loadTokens(t)
+
+	tests := []struct {
+		token, project, site string
+		err                  error
+	}{
+		{"secret", "Example", "", nil},
+		{"", "Example", "", ErrMissingToken},
+		{"wrong", "Example", "", ErrInvalidToken},
+		{"secret", "Other", "", errors.New("token is not valid for project \"Other\"")},
+		{"secret", "Public", "", nil},
+		{"", "Public", "", nil},
+		{"secret", "Other", "ci", errors.New("token is not valid for project \"Other\"")},
+		// sha256("set")
+		{"set", "Other", "ci", nil},
+		{"set", "Other", "laptop", errors.New("token is not valid for site \"laptop\"")},
+	}
+
+	for _, test := range tests {
+		err := tokens.Authorize(test.token, test.project, test.site)
+		if (err == nil) != (test.err == nil) || (err != nil && err.Error() != test.err.Error()) {
+			t.Errorf("Authorize(%q, %q, %q) = %v, expected %v", test.token, test.project, test.site, err, test.err)
+		}
+	}
+}
+
+func TestRejectUnknown(t *testing.T) {
+	tokens := loadTokens(t)
+	tokens.RejectUnknown = true
+
+	if err := tokens.Authorize("secret", "Public", ""); !errors.Is(err, ErrUnknownProject) {
+		t.Errorf("expected unknown project, got %v", err)
+	}
+	if err := tokens.Authorize("secret", "Example", ""); err != nil {
+		t.Errorf("expected success, got %v", err)
+	}
+}
@@ -0,0 +1,53 @@
+// SPDX-FileCopyrightText: 2025 Daniel Pfeifer <daniel@pfeifer-mail.de>
+// SPDX-License-Identifier: ISC
+
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/chorse-dev/cdash-proxy/config"
+)
+
+// baseline prints the fingerprints of the diagnostics in local files that are
+// not suppressed yet, in the format of a baseline file.
+func baseline(args []string) error {
+	cfg := config.Default()
+	fs := flag.NewFlagSet("baseline", flag.ExitOnError)
+	project := fs.String("project", "", "name of the project")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: cdash-proxy baseline [flags] file...")
+		fs.PrintDefaults()
+	}
+	if err := parseFlags(fs, cfg, args); err != nil {
+		return err
+	}
+
+	if fs.NArg() == 0 {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	p := cfg.Profiles().Lookup(*project)
+	w := bufio.NewWriter(os.Stdout)
+	seen := map[string]bool{}
+	for _, name := range fs.Args() {
+		job, err := convertFile(name, *project, "", p)
+		if err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+		for _, cmd := range job.Commands {
+			for _, diag := range cmd.Diagnostics {
+				if diag.Suppressed || seen[diag.Fingerprint] {
+					continue
+				}
+				seen[diag.Fingerprint] = true
+				fmt.Fprintf(w, "%s %s %s:%d %s\n", diag.Fingerprint, diag.Type, diag.FilePath, diag.Line, diag.Message)
+			}
+		}
+	}
+	return w.Flush()
+}
@@ -0,0 +1,111 @@
+// SPDX-FileCopyrightText: 2025 Daniel Pfeifer <daniel@pfeifer-mail.de>
+// SPDX-License-Identifier: ISC
+
+package blob
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+var ErrInvalidSum = errors.New("invalid MD5 checksum")
+
+// ChecksumError is returned if the content does not match the announced
+// checksum.
+type ChecksumError struct {
+	Expected string
+	Actual   string
+}
+
+func (e *ChecksumError) Error() string {
+	return fmt.Sprintf("MD5 mismatch: expected %s, got %s", e.Expected, e.Actual)
+}
+
+// Store keeps uploaded files addressed by their MD5 checksum.
+type Store struct {
+	dir string
+}
+
+func NewStore(dir string) (*Store, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	return &Store{dir: dir}, nil
+}
+
+func (s *Store) path(sum string) (string, error) {
+	sum = strings.ToLower(sum)
+	if len(sum) != 2*md5.Size {
+		return "", ErrInvalidSum
+	}
+	if _, err := hex.DecodeString(sum); err != nil {
+		return "", ErrInvalidSum
+	}
+	return filepath.Join(s.dir, sum[:2], sum), nil
+}
+
+// Has reports whether content with the given checksum is stored.
+func (s *Store) Has(sum string) bool {
+	name, err := s.path(sum)
+	if err != nil {
+		return false
+	}
+	_, err = os.Stat(name)
+	return err == nil
+}
+
+// Open opens the content with the given checksum for reading.
+func (s *Store) Open(sum string) (*os.File, error) {
+	name, err := s.path(sum)
+	if err != nil {
+		return nil, err
+	}
+	return os.Open(name)
+}
+
+// Put stores the content of r, which must match sum.
+func (s *Store) Put(sum string, r io.Reader) error {
+	name, err := s.path(sum)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(name), 0o755); err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(name), ".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if err := Verify(tmp, r, sum); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), name)
+}
+
+// Verify copies r to w and checks that the content matches sum.
+func Verify(w io.Writer, r io.Reader, sum string) error {
+	hasher := md5.New()
+	if _, err := io.Copy(io.MultiWriter(w, hasher), r); err != nil {
+		return err
+	}
+
+	actual := hex.EncodeToString(hasher.Sum(nil))
+	if !strings.EqualFold(actual, sum) {
+		return &ChecksumError{Expected: sum, Actual: actual}
+	}
+	return nil
+}
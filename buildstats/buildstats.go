@@ -0,0 +1,390 @@
+// SPDX-FileCopyrightText: 2025 Daniel Pfeifer <daniel@pfeifer-mail.de>
+// SPDX-License-Identifier: ISC
+
+// Package buildstats reports where the time of an instrumented build goes:
+// the time per target, the slowest sources, the critical path through the
+// target dependencies, and the parallelism over time.
+package buildstats
+
+import (
+	"cmp"
+	"encoding/json"
+	"io"
+	"path"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/chorse-dev/cdash-proxy/model"
+)
+
+// SlowestSources is the number of sources that are reported as slowest.
+const SlowestSources = 10
+
+// Buckets is the maximum number of intervals of the parallelism over time.
+const Buckets = 100
+
+// Report summarizes the build commands of a job. All durations are in
+// milliseconds. The wall time spans the commands that have a start time.
+type Report struct {
+	JobID              string        `json:"job_id"`
+	WallTime           int64         `json:"wall_time"`
+	CPUTime            int64         `json:"cpu_time"`
+	AverageParallelism float64       `json:"average_parallelism"`
+	MaxParallelism     int           `json:"max_parallelism"`
+	Interval           int64         `json:"interval"`
+	Parallelism        []float64     `json:"parallelism"`
+	Targets            []TargetStats `json:"targets"`
+	SlowestSources     []SourceStats `json:"slowest_sources"`
+	CriticalPath       []Step        `json:"critical_path"`
+	CriticalPathTime   int64         `json:"critical_path_time"`
+}
+
+// TargetStats is the accumulated time of the commands of a target. The
+// dependencies are the targets whose outputs are passed to its link command.
+type TargetStats struct {
+	Target       string   `json:"target"`
+	TargetType   string   `json:"target_type,omitempty"`
+	Sources      int      `json:"sources"`
+	Compile      int64    `json:"compile"`
+	Link         int64    `json:"link"`
+	Custom       int64    `json:"custom"`
+	Dependencies []string `json:"dependencies,omitempty"`
+}
+
+type SourceStats struct {
+	Source   string `json:"source"`
+	Target   string `json:"target,omitempty"`
+	Language string `json:"language,omitempty"`
+	Duration int64  `json:"duration"`
+}
+
+// Step is a command on the critical path. Finish is the time from the start
+// of the path until the command finishes.
+type Step struct {
+	Role     string `json:"role"`
+	Target   string `json:"target"`
+	Source   string `json:"source,omitempty"`
+	Duration int64  `json:"duration"`
+	Finish   int64  `json:"finish"`
+}
+
+// Analyze reports on the compile, link and custom commands of a job.
+func Analyze(job *model.Job) *Report {
+	var cmds []*model.Command
+	for i := range job.Commands {
+		switch job.Commands[i].Role {
+		case "compile", "link", "custom":
+			cmds = append(cmds, &job.Commands[i])
+		}
+	}
+
+	report := &Report{
+		JobID:          job.JobID,
+		Parallelism:    []float64{},
+		Targets:        []TargetStats{},
+		SlowestSources: []SourceStats{},
+		CriticalPath:   []Step{},
+	}
+	for _, cmd := range cmds {
+		report.CPUTime += duration(cmd)
+	}
+	report.timeline(cmds)
+
+	g := newGraph(cmds)
+	report.Targets = g.stats()
+	report.SlowestSources = slowestSources(cmds)
+	report.CriticalPath = g.criticalPath()
+	if n := len(report.CriticalPath); n != 0 {
+		report.CriticalPathTime = report.CriticalPath[n-1].Finish
+	}
+	return report
+}
+
+// Write writes the report of a job as JSON.
+func Write(w io.Writer, job *model.Job) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(Analyze(job))
+}
+
+func duration(cmd *model.Command) int64 {
+	return max(cmd.Duration, 0)
+}
+
+// timeline computes the parallelism from the commands that have a start
+// time: the peak number of concurrent commands and the average number per
+// interval.
+func (r *Report) timeline(cmds []*model.Command) {
+	type edge struct {
+		at    time.Time
+		delta int
+	}
+	var edges []edge
+	var first, last time.Time
+	for _, cmd := range cmds {
+		if cmd.StartTime == nil {
+			continue
+		}
+		start := *cmd.StartTime
+		end := start.Add(time.Duration(duration(cmd)) * time.Millisecond)
+		edges = append(edges, edge{start, 1}, edge{end, -1})
+		if first.IsZero() || start.Before(first) {
+			first = start
+		}
+		if end.After(last) {
+			last = end
+		}
+	}
+	if len(edges) == 0 {
+		return
+	}
+
+	// Commands that end free their slot for commands that start at the
+	// same time.
+	slices.SortFunc(edges, func(a, b edge) int {
+		return cmp.Or(a.at.Compare(b.at), cmp.Compare(a.delta, b.delta))
+	})
+	running := 0
+	var busy time.Duration
+	for i, e := range edges {
+		if i != 0 && running != 0 {
+			busy += time.Duration(running) * e.at.Sub(edges[i-1].at)
+		}
+		running += e.delta
+		r.MaxParallelism = max(r.MaxParallelism, running)
+	}
+
+	r.WallTime = last.Sub(first).Milliseconds()
+	if r.WallTime == 0 {
+		return
+	}
+	r.AverageParallelism = busy.Seconds() * 1000 / float64(r.WallTime)
+
+	r.Interval = (r.WallTime + Buckets - 1) / Buckets
+	interval := time.Duration(r.Interval) * time.Millisecond
+	buckets := make([]time.Duration, (r.WallTime+r.Interval-1)/r.Interval)
+	for _, cmd := range cmds {
+		if cmd.StartTime == nil {
+			continue
+		}
+		start := cmd.StartTime.Sub(first)
+		end := start + time.Duration(duration(cmd))*time.Millisecond
+		for i := int(start / interval); i < len(buckets) && time.Duration(i)*interval < end; i++ {
+			lo := max(start, time.Duration(i)*interval)
+			hi := min(end, time.Duration(i+1)*interval)
+			buckets[i] += hi - lo
+		}
+	}
+
+	r.Parallelism = make([]float64, len(buckets))
+	total := last.Sub(first)
+	for i, b := range buckets {
+		length := min(interval, total-time.Duration(i)*interval)
+		r.Parallelism[i] = float64(b) / float64(length)
+	}
+}
+
+func slowestSources(cmds []*model.Command) []SourceStats {
+	sources := []SourceStats{}
+	for _, cmd := range cmds {
+		if cmd.Role == "compile" && cmd.Source != "" {
+			sources = append(sources, SourceStats{
+				Source:   cmd.Source,
+				Target:   cmd.Target,
+				Language: cmd.Language,
+				Duration: duration(cmd),
+			})
+		}
+	}
+	slices.SortStableFunc(sources, func(a, b SourceStats) int {
+		return cmp.Compare(b.Duration, a.Duration)
+	})
+	return sources[:min(len(sources), SlowestSources)]
+}
+
+// target collects the commands of a target. The slowest command that is not
+// a link is assumed to finish last, since the other commands of a target run
+// in parallel.
+type target struct {
+	TargetStats
+	slowest *model.Command
+	links   []*model.Command
+	outputs []string
+}
+
+type graph struct {
+	targets map[string]*target
+	order   []string
+}
+
+func newGraph(cmds []*model.Command) *graph {
+	g := &graph{targets: map[string]*target{}}
+	for _, cmd := range cmds {
+		if cmd.Target == "" {
+			continue
+		}
+		t := g.targets[cmd.Target]
+		if t == nil {
+			t = &target{TargetStats: TargetStats{Target: cmd.Target}}
+			g.targets[cmd.Target] = t
+			g.order = append(g.order, cmd.Target)
+		}
+		if t.TargetType == "" {
+			t.TargetType = cmd.TargetType
+		}
+
+		switch cmd.Role {
+		case "compile":
+			t.Sources++
+			t.Compile += duration(cmd)
+		case "link":
+			t.Link += duration(cmd)
+			t.links = append(t.links, cmd)
+			t.outputs = append(t.outputs, linkOutputs(cmd)...)
+			continue
+		case "custom":
+			t.Custom += duration(cmd)
+		}
+		if t.slowest == nil || duration(cmd) > duration(t.slowest) {
+			t.slowest = cmd
+		}
+	}
+
+	for _, name := range g.order {
+		t := g.targets[name]
+		for _, dep := range g.order {
+			if dep != name && t.dependsOn(g.targets[dep]) {
+				t.Dependencies = append(t.Dependencies, dep)
+			}
+		}
+	}
+	return g
+}
+
+// stats returns the targets, the most expensive first.
+func (g *graph) stats() []TargetStats {
+	stats := []TargetStats{}
+	for _, name := range g.order {
+		stats = append(stats, g.targets[name].TargetStats)
+	}
+	slices.SortStableFunc(stats, func(a, b TargetStats) int {
+		return cmp.Compare(b.Compile+b.Link+b.Custom, a.Compile+a.Link+a.Custom)
+	})
+	return stats
+}
+
+// criticalPath approximates the longest chain of commands. A target is
+// linked once its slowest command and all its dependencies have finished.
+// Dependency cycles are broken where they are found.
+func (g *graph) criticalPath() []Step {
+	finish := map[string]int64{}
+	prev := map[string]string{}
+	visiting := map[string]bool{}
+
+	var visit func(name string) int64
+	visit = func(name string) int64 {
+		if f, found := finish[name]; found {
+			return f
+		}
+		if visiting[name] {
+			return 0
+		}
+		visiting[name] = true
+		defer delete(visiting, name)
+
+		t := g.targets[name]
+		var ready int64
+		if t.slowest != nil {
+			ready = duration(t.slowest)
+		}
+		for _, dep := range t.Dependencies {
+			if f := visit(dep); f > ready {
+				ready = f
+				prev[name] = dep
+			}
+		}
+		finish[name] = ready + t.Link
+		return finish[name]
+	}
+
+	var last string
+	for _, name := range g.order {
+		if f := visit(name); last == "" || f > finish[last] {
+			last = name
+		}
+	}
+	if last == "" {
+		return []Step{}
+	}
+
+	var chain []string
+	for name := last; name != ""; name = prev[name] {
+		chain = append(chain, name)
+	}
+	slices.Reverse(chain)
+
+	path := []Step{}
+	add := func(cmd *model.Command) {
+		var finish int64
+		if n := len(path); n != 0 {
+			finish = path[n-1].Finish
+		}
+		path = append(path, Step{
+			Role:     cmd.Role,
+			Target:   cmd.Target,
+			Source:   cmd.Source,
+			Duration: duration(cmd),
+			Finish:   finish + duration(cmd),
+		})
+	}
+	for i, name := range chain {
+		t := g.targets[name]
+		if i == 0 && t.slowest != nil {
+			add(t.slowest)
+		}
+		for _, link := range t.links {
+			add(link)
+		}
+	}
+	return path
+}
+
+// linkOutputs returns the files that a link command creates: its outputs, or
+// else the argument of "-o" or "/out:", or the archive of "ar".
+func linkOutputs(cmd *model.Command) []string {
+	if len(cmd.Outputs) != 0 {
+		return cmd.Outputs
+	}
+	args := strings.Fields(cmd.CommandLine)
+	for i, arg := range args {
+		switch lower := strings.ToLower(arg); {
+		case arg == "-o" && i+1 < len(args):
+			return []string{args[i+1]}
+		case strings.HasPrefix(lower, "/out:"):
+			return []string{arg[len("/out:"):]}
+		}
+	}
+	if len(args) > 2 && strings.HasSuffix(path.Base(args[0]), "ar") {
+		return []string{args[2]}
+	}
+	return nil
+}
+
+// dependsOn reports whether the link command of t consumes an output of dep,
+// either by path or as "-l<name>" for "lib<name>.*".
+func (t *target) dependsOn(dep *target) bool {
+	for _, link := range t.links {
+		args := strings.Fields(link.CommandLine)
+		for _, out := range dep.outputs {
+			base := path.Base(out)
+			name, _, _ := strings.Cut(strings.TrimPrefix(base, "lib"), ".")
+			for _, arg := range args {
+				if path.Clean(arg) == path.Clean(out) || arg == "-l"+name {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,107 @@
+// SPDX-FileCopyrightText: 2025 Daniel Pfeifer <daniel@pfeifer-mail.de>
+// SPDX-License-Identifier: ISC
+
+package buildstats
+
+import (
+	"testing"
+	"time"
+
+	"github.com/chorse-dev/cdash-proxy/model"
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+)
+
+func TestAnalyze(t *testing.T) {
+	t0 := time.Date(2025, 5, 19, 20, 0, 0, 0, time.UTC)
+	at := func(ms int) *time.Time {
+		t := t0.Add(time.Duration(ms) * time.Millisecond)
+		return &t
+	}
+
+	job := &model.Job{
+		JobID: "job",
+		Commands: []model.Command{
+			{Role: "cmakeBuild", StartTime: at(0), Duration: 400},
+			{Role: "compile", Target: "core", TargetType: "STATIC_LIBRARY", Source: "a.c",
+				StartTime: at(0), Duration: 100},
+			{Role: "compile", Target: "core", TargetType: "STATIC_LIBRARY", Source: "b.c",
+				StartTime: at(0), Duration: 300},
+			{Role: "compile", Target: "app", TargetType: "EXECUTABLE", Source: "main.c",
+				StartTime: at(0), Duration: 200},
+			{Role: "link", Target: "core", TargetType: "STATIC_LIBRARY",
+				CommandLine: "/usr/bin/ar qc libcore.a a.c.o b.c.o", StartTime: at(300), Duration: 50},
+			{Role: "link", Target: "app", TargetType: "EXECUTABLE",
+				CommandLine: "/usr/bin/cc main.c.o -o app libcore.a", StartTime: at(350), Duration: 20},
+			{Role: "compile", Target: "app", Source: "failed.c", Result: 1},
+			{Role: "test", TestName: "app", Duration: 1000},
+		},
+	}
+
+	got := Analyze(job)
+	if len(got.Parallelism) != 93 || got.Parallelism[0] != 3 || got.Parallelism[92] != 1 {
+		t.Errorf("unexpected parallelism %v", got.Parallelism)
+	}
+	got.Parallelism = nil
+
+	want := &Report{
+		JobID:              "job",
+		WallTime:           370,
+		CPUTime:            670,
+		AverageParallelism: 670.0 / 370,
+		MaxParallelism:     3,
+		Interval:           4,
+		Targets: []TargetStats{
+			{Target: "core", TargetType: "STATIC_LIBRARY", Sources: 2, Compile: 400, Link: 50},
+			{Target: "app", TargetType: "EXECUTABLE", Sources: 2, Compile: 200, Link: 20,
+				Dependencies: []string{"core"}},
+		},
+		SlowestSources: []SourceStats{
+			{Source: "b.c", Target: "core", Duration: 300},
+			{Source: "main.c", Target: "app", Duration: 200},
+			{Source: "a.c", Target: "core", Duration: 100},
+			{Source: "failed.c", Target: "app", Duration: 0},
+		},
+		CriticalPath: []Step{
+			{Role: "compile", Target: "core", Source: "b.c", Duration: 300, Finish: 300},
+			{Role: "link", Target: "core", Duration: 50, Finish: 350},
+			{Role: "link", Target: "app", Duration: 20, Finish: 370},
+		},
+		CriticalPathTime: 370,
+	}
+	if diff := cmp.Diff(want, got, cmpopts.EquateApprox(0, 1e-9)); diff != "" {
+		t.Errorf("Analyze() mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestAnalyzeWithoutBuild(t *testing.T) {
+	got := Analyze(&model.Job{JobID: "job"})
+	want := &Report{
+		JobID:          "job",
+		Parallelism:    []float64{},
+		Targets:        []TargetStats{},
+		SlowestSources: []SourceStats{},
+		CriticalPath:   []Step{},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("Analyze() mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestDependsOn(t *testing.T) {
+	dep := &target{outputs: []string{"lib/libutil.so"}}
+	for _, line := range []string{
+		"c++ main.o -o app lib/libutil.so",
+		"c++ main.o -o app ./lib/libutil.so",
+		"c++ main.o -o app -Llib -lutil",
+	} {
+		tgt := &target{links: []*model.Command{{CommandLine: line}}}
+		if !tgt.dependsOn(dep) {
+			t.Errorf("%q does not depend on %v", line, dep.outputs)
+		}
+	}
+	tgt := &target{links: []*model.Command{{CommandLine: "c++ main.o -o app -lutility"}}}
+	if tgt.dependsOn(dep) {
+		t.Errorf("unexpected dependency")
+	}
+}
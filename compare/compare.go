@@ -0,0 +1,395 @@
+// SPDX-FileCopyrightText: 2025 Daniel Pfeifer <daniel@pfeifer-mail.de>
+// SPDX-License-Identifier: ISC
+
+// Package compare reports what changed between a job and a baseline job,
+// usually the previous job of the same build.
+package compare
+
+import (
+	"maps"
+	"math"
+	"slices"
+
+	"github.com/chorse-dev/cdash-proxy/fingerprint"
+	"github.com/chorse-dev/cdash-proxy/model"
+)
+
+// Report is the difference between a job and its baseline. Lists contain
+// only entries that changed.
+type Report struct {
+	JobID         string             `json:"job_id"`
+	BaselineID    string             `json:"baseline_id"`
+	Commands      []RoleDelta        `json:"commands"`
+	Diagnostics   DiagnosticDelta    `json:"diagnostics"`
+	Tests         []TestTransition   `json:"tests"`
+	Measurements  []MeasurementDelta `json:"measurements"`
+	Coverage      []CoverageDelta    `json:"coverage"`
+	TotalCoverage CoverageDelta      `json:"total_coverage"`
+}
+
+// RoleDelta compares the commands of a role. Failed commands have a non-zero
+// result or, for tests, a status other than "passed". Durations are in
+// milliseconds.
+type RoleDelta struct {
+	Role             string `json:"role"`
+	Count            int    `json:"count"`
+	BaselineCount    int    `json:"baseline_count"`
+	Failed           int    `json:"failed"`
+	BaselineFailed   int    `json:"baseline_failed"`
+	Duration         int64  `json:"duration"`
+	BaselineDuration int64  `json:"baseline_duration"`
+}
+
+// DiagnosticDelta lists the diagnostics whose fingerprints appear only in the
+// job (New) or only in the baseline (Fixed). Suppressed diagnostics are not
+// compared.
+type DiagnosticDelta struct {
+	New       []Diagnostic `json:"new"`
+	Fixed     []Diagnostic `json:"fixed"`
+	Unchanged int          `json:"unchanged"`
+}
+
+// Diagnostic is a diagnostic together with the command that reported it.
+type Diagnostic struct {
+	model.Diagnostic
+	Role     string `json:"role"`
+	Target   string `json:"target,omitempty"`
+	Source   string `json:"source,omitempty"`
+	TestName string `json:"test_name,omitempty"`
+}
+
+// TestTransition is a test whose status changed. Tests that are new have no
+// baseline status, tests that were removed have no status.
+type TestTransition struct {
+	TestName       string `json:"test_name"`
+	Status         string `json:"status"`
+	BaselineStatus string `json:"baseline_status"`
+}
+
+// MeasurementDelta is a numeric measurement of a test that changed. The
+// duration of tests is reported as measurement "Duration" in milliseconds if
+// it changed by at least 10 milliseconds and 10 percent.
+type MeasurementDelta struct {
+	TestName string  `json:"test_name"`
+	Name     string  `json:"name"`
+	Value    float64 `json:"value"`
+	Baseline float64 `json:"baseline"`
+	Delta    float64 `json:"delta"`
+}
+
+// CoverageDelta compares the line coverage of a file in percent. It is nil
+// if the file has no coverage in the job or the baseline.
+type CoverageDelta struct {
+	FilePath string   `json:"file_path,omitempty"`
+	Value    *float64 `json:"value"`
+	Baseline *float64 `json:"baseline"`
+	Delta    float64  `json:"delta"`
+}
+
+// DurationName is the name of the measurement for the duration of tests.
+const DurationName = "Duration"
+
+// Durations vary from run to run. A duration is reported as changed only if
+// it differs by at least durationDelta milliseconds and durationRatio of the
+// baseline.
+const (
+	durationDelta = 10
+	durationRatio = 0.1
+)
+
+// Compare reports the changes from baseline to job.
+func Compare(job, baseline *model.Job) *Report {
+	return &Report{
+		JobID:         job.JobID,
+		BaselineID:    baseline.JobID,
+		Commands:      compareRoles(job, baseline),
+		Diagnostics:   compareDiagnostics(job, baseline),
+		Tests:         compareTests(job, baseline),
+		Measurements:  compareMeasurements(job, baseline),
+		Coverage:      compareCoverage(job, baseline),
+		TotalCoverage: compareTotalCoverage(job, baseline),
+	}
+}
+
+func failed(cmd *model.Command) bool {
+	if cmd.Role == "test" {
+		return cmd.TestStatus != "passed"
+	}
+	return cmd.Result != 0
+}
+
+// analysisOnly returns whether a command of the job holds only the results
+// of a dynamic analysis checker: it has no status, or the test is reported
+// by another command, which is compared instead. A test that was merged with
+// its dynamic analysis is compared like any other test.
+func analysisOnly(job *model.Job) func(*model.Command) bool {
+	tested := map[string]bool{}
+	for i := range job.Commands {
+		cmd := &job.Commands[i]
+		if cmd.Role == "test" && cmd.Attributes["DA Checker"] == "" {
+			tested[cmd.TestName] = true
+		}
+	}
+	return func(cmd *model.Command) bool {
+		return cmd.Role == "test" && cmd.Attributes["DA Checker"] != "" &&
+			(cmd.TestStatus == "" || tested[cmd.TestName])
+	}
+}
+
+func compareRoles(job, baseline *model.Job) []RoleDelta {
+	roles := map[string]*RoleDelta{}
+	role := func(name string) *RoleDelta {
+		if roles[name] == nil {
+			roles[name] = &RoleDelta{Role: name}
+		}
+		return roles[name]
+	}
+
+	skip := analysisOnly(job)
+	for _, cmd := range job.Commands {
+		if skip(&cmd) {
+			continue
+		}
+		r := role(cmd.Role)
+		r.Count++
+		r.Duration += cmd.Duration
+		if failed(&cmd) {
+			r.Failed++
+		}
+	}
+	skip = analysisOnly(baseline)
+	for _, cmd := range baseline.Commands {
+		if skip(&cmd) {
+			continue
+		}
+		r := role(cmd.Role)
+		r.BaselineCount++
+		r.BaselineDuration += cmd.Duration
+		if failed(&cmd) {
+			r.BaselineFailed++
+		}
+	}
+
+	deltas := []RoleDelta{}
+	for _, name := range slices.Sorted(maps.Keys(roles)) {
+		deltas = append(deltas, *roles[name])
+	}
+	return deltas
+}
+
+// diagnostics returns the unsuppressed diagnostics of the job by fingerprint.
+func diagnostics(job *model.Job) (map[string]Diagnostic, []string) {
+	byFingerprint := map[string]Diagnostic{}
+	var order []string
+	for _, cmd := range job.Commands {
+		for _, diag := range cmd.Diagnostics {
+			if diag.Suppressed {
+				continue
+			}
+			fp := diag.Fingerprint
+			if fp == "" {
+				fp = fingerprint.Diagnostic(&diag, "")
+			}
+			if _, found := byFingerprint[fp]; found {
+				continue
+			}
+			byFingerprint[fp] = Diagnostic{
+				Diagnostic: diag,
+				Role:       cmd.Role,
+				Target:     cmd.Target,
+				Source:     cmd.Source,
+				TestName:   cmd.TestName,
+			}
+			order = append(order, fp)
+		}
+	}
+	return byFingerprint, order
+}
+
+func compareDiagnostics(job, baseline *model.Job) DiagnosticDelta {
+	current, currentOrder := diagnostics(job)
+	previous, previousOrder := diagnostics(baseline)
+
+	delta := DiagnosticDelta{New: []Diagnostic{}, Fixed: []Diagnostic{}}
+	for _, fp := range currentOrder {
+		if _, found := previous[fp]; found {
+			delta.Unchanged++
+		} else {
+			delta.New = append(delta.New, current[fp])
+		}
+	}
+	for _, fp := range previousOrder {
+		if _, found := current[fp]; !found {
+			delta.Fixed = append(delta.Fixed, previous[fp])
+		}
+	}
+	return delta
+}
+
+// tests returns the tests of the job by name. Commands that hold only the
+// dynamic analysis of a test are not included.
+func tests(job *model.Job) map[string]*model.Command {
+	byName := map[string]*model.Command{}
+	skip := analysisOnly(job)
+	for i := range job.Commands {
+		cmd := &job.Commands[i]
+		if cmd.Role == "test" && !skip(cmd) {
+			byName[cmd.TestName] = cmd
+		}
+	}
+	return byName
+}
+
+func compareTests(job, baseline *model.Job) []TestTransition {
+	current, previous := tests(job), tests(baseline)
+
+	transitions := []TestTransition{}
+	names := slices.Sorted(maps.Keys(current))
+	for name := range previous {
+		if current[name] == nil {
+			names = append(names, name)
+		}
+	}
+	slices.Sort(names)
+
+	for _, name := range names {
+		var t TestTransition
+		t.TestName = name
+		if cmd := current[name]; cmd != nil {
+			t.Status = cmd.TestStatus
+		}
+		if cmd := previous[name]; cmd != nil {
+			t.BaselineStatus = cmd.TestStatus
+		}
+		if t.Status != t.BaselineStatus {
+			transitions = append(transitions, t)
+		}
+	}
+	return transitions
+}
+
+func compareMeasurements(job, baseline *model.Job) []MeasurementDelta {
+	current, previous := tests(job), tests(baseline)
+
+	deltas := []MeasurementDelta{}
+	for _, name := range slices.Sorted(maps.Keys(current)) {
+		cmd, prev := current[name], previous[name]
+		if prev == nil {
+			continue
+		}
+
+		add := func(measurement string, value, base float64) {
+			if value != base {
+				deltas = append(deltas, MeasurementDelta{
+					TestName: name,
+					Name:     measurement,
+					Value:    value,
+					Baseline: base,
+					Delta:    value - base,
+				})
+			}
+		}
+
+		if durationChanged(cmd.Duration, prev.Duration) {
+			add(DurationName, float64(cmd.Duration), float64(prev.Duration))
+		}
+		for _, m := range slices.Sorted(maps.Keys(cmd.Measurements)) {
+			if base, found := prev.Measurements[m]; found {
+				add(m, cmd.Measurements[m], base)
+			}
+		}
+	}
+	return deltas
+}
+
+func durationChanged(value, base int64) bool {
+	delta := math.Abs(float64(value - base))
+	return delta >= durationDelta && delta >= durationRatio*float64(base)
+}
+
+// lineCoverage returns the numbers of tested and untested lines.
+func lineCoverage(c *model.Coverage) (tested, untested int) {
+	if c.LinesTested != nil && c.LinesUntested != nil {
+		return *c.LinesTested, *c.LinesUntested
+	}
+	for _, count := range c.Lines {
+		switch {
+		case count > 0:
+			tested++
+		case count == 0:
+			untested++
+		}
+	}
+	return tested, untested
+}
+
+func percent(tested, untested int) *float64 {
+	if tested+untested == 0 {
+		return nil
+	}
+	p := 100 * float64(tested) / float64(tested+untested)
+	return &p
+}
+
+func coverage(job *model.Job) map[string]*float64 {
+	byFile := map[string]*float64{}
+	for i := range job.Coverage {
+		byFile[job.Coverage[i].FilePath] = percent(lineCoverage(&job.Coverage[i]))
+	}
+	return byFile
+}
+
+func newCoverageDelta(path string, value, baseline *float64) CoverageDelta {
+	return CoverageDelta{
+		FilePath: path,
+		Value:    value,
+		Baseline: baseline,
+		Delta:    deref(value) - deref(baseline),
+	}
+}
+
+func deref(p *float64) float64 {
+	if p == nil {
+		return 0
+	}
+	return *p
+}
+
+func compareCoverage(job, baseline *model.Job) []CoverageDelta {
+	current, previous := coverage(job), coverage(baseline)
+
+	paths := slices.Sorted(maps.Keys(current))
+	for path := range previous {
+		if _, found := current[path]; !found {
+			paths = append(paths, path)
+		}
+	}
+	slices.Sort(paths)
+
+	deltas := []CoverageDelta{}
+	for _, path := range paths {
+		value, base := current[path], previous[path]
+		if value == nil && base == nil {
+			continue
+		}
+		if value != nil && base != nil && *value == *base {
+			continue
+		}
+		deltas = append(deltas, newCoverageDelta(path, value, base))
+	}
+	return deltas
+}
+
+func totalCoverage(job *model.Job) *float64 {
+	var tested, untested int
+	for i := range job.Coverage {
+		t, u := lineCoverage(&job.Coverage[i])
+		tested += t
+		untested += u
+	}
+	return percent(tested, untested)
+}
+
+func compareTotalCoverage(job, baseline *model.Job) CoverageDelta {
+	return newCoverageDelta("", totalCoverage(job), totalCoverage(baseline))
+}
@@ -0,0 +1,148 @@
+// SPDX-FileCopyrightText: 2025 Daniel Pfeifer <daniel@pfeifer-mail.de>
+// SPDX-License-Identifier: ISC
+
+package compare
+
+import (
+	"encoding/json"
+	"os"
+	"testing"
+
+	"github.com/chorse-dev/cdash-proxy/aggregate"
+	"github.com/chorse-dev/cdash-proxy/model"
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+)
+
+func TestCompare(t *testing.T) {
+	unused := model.Diagnostic{FilePath: "a.c", Line: 7, Type: "Warning", Message: "unused variable 'x'", Fingerprint: "1111"}
+	format := model.Diagnostic{FilePath: "a.c", Line: 10, Type: "Warning", Message: "format '%d'", Fingerprint: "2222"}
+	known := model.Diagnostic{FilePath: "b.c", Line: 3, Type: "Warning", Message: "shadow", Fingerprint: "3333",
+		Suppressed: true, SuppressedBy: "baseline"}
+
+	baseline := &model.Job{
+		JobID: "old",
+		Commands: []model.Command{
+			{Role: "compile", Source: "a.c", Duration: 100, Diagnostics: []model.Diagnostic{unused}},
+			{Role: "test", TestName: "alpha", TestStatus: "passed", Duration: 50,
+				Measurements: map[string]float64{"Memory": 10}},
+			{Role: "test", TestName: "beta", TestStatus: "passed", Duration: 20},
+			{Role: "test", TestName: "gone", TestStatus: "failed"},
+		},
+		Coverage: []model.Coverage{
+			{FilePath: "a.c", Lines: []int{-1, 1, 1, 0, 0}},
+			{FilePath: "b.c", Lines: []int{1, 1}},
+		},
+	}
+	job := &model.Job{
+		JobID: "new",
+		Commands: []model.Command{
+			{Role: "compile", Source: "a.c", Result: 1, Duration: 120, Diagnostics: []model.Diagnostic{format, known}},
+			{Role: "test", TestName: "alpha", TestStatus: "passed", Duration: 80,
+				Measurements: map[string]float64{"Memory": 10}},
+			{Role: "test", TestName: "beta", TestStatus: "failed", Duration: 20},
+			{Role: "test", TestName: "beta", TestStatus: "failed",
+				Attributes: map[string]string{"DA Checker": "Valgrind"}},
+		},
+		Coverage: []model.Coverage{
+			{FilePath: "a.c", Lines: []int{-1, 1, 1, 1, 0}},
+			{FilePath: "b.c", Lines: []int{1, 1}},
+		},
+	}
+
+	ptr := func(f float64) *float64 { return &f }
+	want := &Report{
+		JobID:      "new",
+		BaselineID: "old",
+		Commands: []RoleDelta{
+			{Role: "compile", Count: 1, BaselineCount: 1, Failed: 1, Duration: 120, BaselineDuration: 100},
+			{Role: "test", Count: 2, BaselineCount: 3, Failed: 1, BaselineFailed: 1, Duration: 100, BaselineDuration: 70},
+		},
+		Diagnostics: DiagnosticDelta{
+			New:   []Diagnostic{{Diagnostic: format, Role: "compile", Source: "a.c"}},
+			Fixed: []Diagnostic{{Diagnostic: unused, Role: "compile", Source: "a.c"}},
+		},
+		Tests: []TestTransition{
+			{TestName: "beta", Status: "failed", BaselineStatus: "passed"},
+			{TestName: "gone", BaselineStatus: "failed"},
+		},
+		Measurements: []MeasurementDelta{
+			{TestName: "alpha", Name: DurationName, Value: 80, Baseline: 50, Delta: 30},
+		},
+		Coverage: []CoverageDelta{
+			{FilePath: "a.c", Value: ptr(75), Baseline: ptr(50), Delta: 25},
+		},
+		TotalCoverage: CoverageDelta{Value: ptr(5.0 / 6 * 100), Baseline: ptr(4.0 / 6 * 100), Delta: 100.0 / 6},
+	}
+
+	if diff := cmp.Diff(want, Compare(job, baseline), cmpopts.EquateApprox(0, 1e-9)); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestCompareDurations(t *testing.T) {
+	durations := func(id string, ms ...int64) *model.Job {
+		job := &model.Job{JobID: id}
+		for i, d := range ms {
+			job.Commands = append(job.Commands, model.Command{
+				Role: "test", TestName: string(rune('a' + i)), TestStatus: "passed", Duration: d,
+			})
+		}
+		return job
+	}
+	// Changes of a few milliseconds or a few percent are noise.
+	baseline := durations("old", 10, 1000, 1000, 100)
+	job := durations("new", 14, 1050, 1200, 80)
+
+	want := []MeasurementDelta{
+		{TestName: "c", Name: DurationName, Value: 1200, Baseline: 1000, Delta: 200},
+		{TestName: "d", Name: DurationName, Value: 80, Baseline: 100, Delta: -20},
+	}
+	if diff := cmp.Diff(want, Compare(job, baseline).Measurements); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+}
+
+// memcheckJob merges the tests of a memcheck build with their dynamic
+// analysis.
+func memcheckJob(t *testing.T) *model.Job {
+	job := &model.Job{}
+	for _, name := range []string{"DynamicAnalysis-Test", "DynamicAnalysis"} {
+		data, err := os.ReadFile("../ctestxml/testdata/" + name + ".json")
+		if err != nil {
+			t.Fatal(err)
+		}
+		var part model.Job
+		if err := json.Unmarshal(data, &part); err != nil {
+			t.Fatal(err)
+		}
+		aggregate.Merge(job, &part)
+	}
+	return job
+}
+
+func TestCompareMemcheck(t *testing.T) {
+	job, baseline := memcheckJob(t), memcheckJob(t)
+	for i := range baseline.Commands {
+		if cmd := &baseline.Commands[i]; cmd.TestName == "Failures.FPE" {
+			cmd.TestStatus = "passed"
+		}
+	}
+
+	report := Compare(job, baseline)
+	tests := 0
+	for _, cmd := range job.Commands {
+		if cmd.Role == "test" {
+			tests++
+		}
+	}
+	want := []TestTransition{{TestName: "Failures.FPE", Status: "failed", BaselineStatus: "passed"}}
+	if diff := cmp.Diff(want, report.Tests); diff != "" {
+		t.Errorf("tests mismatch (-want +got):\n%s", diff)
+	}
+	for _, r := range report.Commands {
+		if r.Role == "test" && (r.Count != tests || r.BaselineCount != tests) {
+			t.Errorf("expected %d tests, got %d and %d", tests, r.Count, r.BaselineCount)
+		}
+	}
+}
@@ -0,0 +1,225 @@
+// SPDX-FileCopyrightText: 2025 Daniel Pfeifer <daniel@pfeifer-mail.de>
+// SPDX-License-Identifier: ISC
+
+// Package config holds the settings of the server. Settings are read from a
+// JSON file and may be overridden by environment variables and flags.
+package config
+
+import (
+	"bytes"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"time"
+
+	"github.com/chorse-dev/cdash-proxy/profile"
+)
+
+// Config holds the settings of the server.
+type Config struct {
+	Listen          []string `json:"listen"`
+	TLSCert         string   `json:"tls_cert"`
+	TLSKey          string   `json:"tls_key"`
+	ReadTimeout     Duration `json:"read_timeout"`
+	WriteTimeout    Duration `json:"write_timeout"`
+	ShutdownTimeout Duration `json:"shutdown_timeout"`
+
+	// MaxBodySize limits the size of uploads in bytes; zero means no limit.
+	MaxBodySize int64 `json:"max_body_size"`
+
+	Sinks       []string `json:"sinks"`
+	IdleTimeout Duration `json:"idle_timeout"`
+
+	Spool   string `json:"spool"`
+	Workers int    `json:"workers"`
+	Retries int    `json:"retries"`
+	Archive string `json:"archive"`
+	Uploads string `json:"uploads"`
+
+	Tokens                string `json:"tokens"`
+	RejectUnknownProjects bool   `json:"reject_unknown_projects"`
+
+	Upstream        string `json:"upstream"`
+	UpstreamPrimary bool   `json:"upstream_primary"`
+	UpstreamFailed  string `json:"upstream_failed"`
+
+	Projects map[string]*Project `json:"projects"`
+}
+
+// Project holds the settings that differ between projects.
+type Project struct {
+	// MaxBodySize overrides the global limit for the uploads of the
+	// project.
+	MaxBodySize int64 `json:"max_body_size,omitempty"`
+
+	profile.Profile
+}
+
+// Default returns the settings that apply unless overridden.
+func Default() *Config {
+	return &Config{
+		Listen:          []string{":8080"},
+		ReadTimeout:     Duration(10 * time.Minute),
+		WriteTimeout:    Duration(10 * time.Minute),
+		ShutdownTimeout: Duration(30 * time.Second),
+		IdleTimeout:     Duration(30 * time.Minute),
+		Workers:         4,
+		Retries:         5,
+	}
+}
+
+// Load reads the settings from a JSON file. Settings that are missing in the
+// file keep their current value.
+func (c *Config) Load(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(c); err != nil {
+		var serr *json.SyntaxError
+		if errors.As(err, &serr) {
+			line := 1 + bytes.Count(data[:serr.Offset], []byte("\n"))
+			return fmt.Errorf("%s:%d: %w", path, line, err)
+		}
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	return nil
+}
+
+// Project returns the settings of the named project, which are empty if the
+// project is not configured.
+func (c *Config) Project(name string) *Project {
+	if p := c.Projects[name]; p != nil {
+		return p
+	}
+	return &Project{}
+}
+
+// BodyLimit returns the maximum size of uploads for the named project.
+func (c *Config) BodyLimit(project string) int64 {
+	if n := c.Project(project).MaxBodySize; n != 0 {
+		return n
+	}
+	return c.MaxBodySize
+}
+
+// Profiles returns the processing profiles of the projects. The profiles are
+// compiled by Validate.
+func (c *Config) Profiles() profile.Registry {
+	r := profile.Registry{}
+	for name, p := range c.Projects {
+		if p != nil {
+			r[name] = &p.Profile
+		}
+	}
+	return r
+}
+
+// Validate reports all invalid settings.
+func (c *Config) Validate() error {
+	var errs []error
+	errorf := func(format string, args ...any) {
+		errs = append(errs, fmt.Errorf(format, args...))
+	}
+
+	if len(c.Listen) == 0 {
+		errorf("listen: no address")
+	}
+	for _, addr := range c.Listen {
+		if _, _, err := net.SplitHostPort(addr); err != nil {
+			errorf("listen: %w", err)
+		}
+	}
+
+	switch {
+	case c.TLSCert == "" && c.TLSKey != "":
+		errorf("tls_key: requires tls_cert")
+	case c.TLSCert != "" && c.TLSKey == "":
+		errorf("tls_cert: requires tls_key")
+	case c.TLSCert != "":
+		if _, err := tls.LoadX509KeyPair(c.TLSCert, c.TLSKey); err != nil {
+			errorf("tls_cert: %w", err)
+		}
+	}
+
+	for _, d := range []struct {
+		name  string
+		value Duration
+	}{
+		{"read_timeout", c.ReadTimeout},
+		{"write_timeout", c.WriteTimeout},
+		{"shutdown_timeout", c.ShutdownTimeout},
+		{"idle_timeout", c.IdleTimeout},
+	} {
+		if d.value < 0 {
+			errorf("%s: must not be negative", d.name)
+		}
+	}
+
+	if c.MaxBodySize < 0 {
+		errorf("max_body_size: must not be negative")
+	}
+	if c.Workers < 1 {
+		errorf("workers: must be at least 1")
+	}
+	if c.Retries < 0 {
+		errorf("retries: must not be negative")
+	}
+
+	if c.Upstream != "" {
+		u, err := url.Parse(c.Upstream)
+		if err != nil {
+			errorf("upstream: %w", err)
+		} else if u.Scheme != "http" && u.Scheme != "https" {
+			errorf("upstream: %q is not an HTTP URL", c.Upstream)
+		}
+	} else if c.UpstreamPrimary || c.UpstreamFailed != "" {
+		errorf("upstream_primary, upstream_failed: require upstream")
+	}
+
+	for name, p := range c.Projects {
+		if name == "" {
+			errorf("projects: empty project name")
+		}
+		if p == nil {
+			continue
+		}
+		if p.MaxBodySize < 0 {
+			errorf("projects.%s.max_body_size: must not be negative", name)
+		}
+		if err := p.Compile(); err != nil {
+			for _, err := range err.(interface{ Unwrap() []error }).Unwrap() {
+				errorf("projects.%s.%w", name, err)
+			}
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// Duration is a time.Duration that is written as string in JSON, like "30s".
+type Duration time.Duration
+
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	v, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	*d = Duration(v)
+	return nil
+}
@@ -0,0 +1,106 @@
+// SPDX-FileCopyrightText: 2025 Daniel Pfeifer <daniel@pfeifer-mail.de>
+// SPDX-License-Identifier: ISC
+
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/chorse-dev/cdash-proxy/profile"
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+)
+
+func TestLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	os.WriteFile(path, []byte(`{
+  "listen": [":8443"],
+  "read_timeout": "1m",
+  "sinks": ["stdout"],
+  "projects": {"Example": {"max_body_size": 1024, "strip_source_path": false}}
+}`), 0o644)
+
+	env := map[string]string{
+		"CDASH_PROXY_SINKS":         "stdout,dir:/tmp/jobs",
+		"CDASH_PROXY_MAX_BODY_SIZE": "4096",
+	}
+
+	c := Default()
+	if err := c.Load(path); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.ApplyEnv(func(key string) (string, bool) {
+		v, found := env[key]
+		return v, found
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Validate(); err != nil {
+		t.Fatal(err)
+	}
+
+	want := Default()
+	want.Listen = []string{":8443"}
+	want.ReadTimeout = Duration(time.Minute)
+	want.Sinks = []string{"stdout", "dir:/tmp/jobs"}
+	want.MaxBodySize = 4096
+	want.Projects = map[string]*Project{"Example": {MaxBodySize: 1024}}
+	want.Projects["Example"].StripSourcePath = new(bool)
+	if diff := cmp.Diff(want, c, cmpopts.IgnoreUnexported(profile.Profile{})); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+
+	if got := c.BodyLimit("Example"); got != 1024 {
+		t.Errorf("BodyLimit(Example) = %d", got)
+	}
+	if got := c.BodyLimit("Other"); got != 4096 {
+		t.Errorf("BodyLimit(Other) = %d", got)
+	}
+}
+
+func TestLoadError(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	os.WriteFile(path, []byte("{\n  \"listen\": [\":8080\"],\n  \"workers\" 4\n}"), 0o644)
+
+	err := Default().Load(path)
+	if err == nil || !strings.Contains(err.Error(), "config.json:3:") {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	os.WriteFile(path, []byte(`{"listen_address": ":8080"}`), 0o644)
+	err = Default().Load(path)
+	if err == nil || !strings.Contains(err.Error(), "listen_address") {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	c := Default()
+	c.Listen = []string{"localhost"}
+	c.Workers = 0
+	c.Projects = map[string]*Project{"Example": {}}
+	c.Projects["Example"].Diagnostics = []string{"(?P<file>"}
+
+	want := []string{
+		"listen: address localhost: missing port in address",
+		"workers: must be at least 1",
+		"projects.Example.diagnostics: error parsing regexp",
+	}
+	err := c.Validate()
+	if err == nil {
+		t.Fatal("expected error")
+	}
+	lines := strings.Split(err.Error(), "\n")
+	if len(lines) != len(want) {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for i := range want {
+		if !strings.HasPrefix(lines[i], want[i]) {
+			t.Errorf("got %q, want prefix %q", lines[i], want[i])
+		}
+	}
+}
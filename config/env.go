@@ -0,0 +1,79 @@
+// SPDX-FileCopyrightText: 2025 Daniel Pfeifer <daniel@pfeifer-mail.de>
+// SPDX-License-Identifier: ISC
+
+package config
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// EnvPrefix is prepended to the upper-cased JSON names of the settings to
+// form the names of the environment variables, like CDASH_PROXY_LISTEN.
+const EnvPrefix = "CDASH_PROXY_"
+
+// ApplyEnv overrides settings with the environment variables that lookup
+// finds. Lists are separated by commas. Projects cannot be set this way.
+func (c *Config) ApplyEnv(lookup func(key string) (string, bool)) error {
+	var errs []error
+	v := reflect.ValueOf(c).Elem()
+	t := v.Type()
+	for i := range t.NumField() {
+		name, _, _ := strings.Cut(t.Field(i).Tag.Get("json"), ",")
+		key := EnvPrefix + strings.ToUpper(name)
+		s, found := lookup(key)
+		if !found {
+			continue
+		}
+		if err := setValue(v.Field(i), s); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", key, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+var durationType = reflect.TypeFor[Duration]()
+
+func setValue(v reflect.Value, s string) error {
+	if v.Type() == durationType {
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return err
+		}
+		v.SetInt(int64(d))
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(s)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return err
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int64:
+		n, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			return err
+		}
+		v.SetInt(n)
+	case reflect.Slice:
+		if v.Type().Elem().Kind() != reflect.String {
+			return errors.New("not supported")
+		}
+		var list []string
+		if s != "" {
+			list = strings.Split(s, ",")
+		}
+		v.Set(reflect.ValueOf(list))
+	default:
+		return errors.New("not supported")
+	}
+	return nil
+}
@@ -0,0 +1,165 @@
+// SPDX-FileCopyrightText: 2025 Daniel Pfeifer <daniel@pfeifer-mail.de>
+// SPDX-License-Identifier: ISC
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/chorse-dev/cdash-proxy/aggregate"
+	"github.com/chorse-dev/cdash-proxy/buildstats"
+	"github.com/chorse-dev/cdash-proxy/config"
+	"github.com/chorse-dev/cdash-proxy/coverage"
+	"github.com/chorse-dev/cdash-proxy/ctestxml"
+	"github.com/chorse-dev/cdash-proxy/gcovtar"
+	"github.com/chorse-dev/cdash-proxy/junit"
+	"github.com/chorse-dev/cdash-proxy/model"
+	"github.com/chorse-dev/cdash-proxy/otlp"
+	"github.com/chorse-dev/cdash-proxy/profile"
+	"github.com/chorse-dev/cdash-proxy/sarif"
+	"github.com/chorse-dev/cdash-proxy/trace"
+)
+
+// convert parses local files without the HTTP server.
+func convert(args []string) error {
+	cfg := config.Default()
+	fs := flag.NewFlagSet("convert", flag.ExitOnError)
+	project := fs.String("project", "", "name of the project")
+	buildID := fs.String("buildid", "", "job ID for GcovTar files")
+	merge := fs.Bool("merge", false, "merge all files into one job")
+	output := fs.String("o", "", "write to `file` instead of stdout")
+	format := fs.String("format", "json", "output `format`: json, sarif, junit, cobertura, lcov, trace, otlp or buildstats")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: cdash-proxy convert [flags] file...")
+		fs.PrintDefaults()
+	}
+	if err := parseFlags(fs, cfg, args); err != nil {
+		return err
+	}
+
+	if fs.NArg() == 0 {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	write, found := formats[*format]
+	if !found {
+		return fmt.Errorf("unknown format %q", *format)
+	}
+
+	p := cfg.Profiles().Lookup(*project)
+	var jobs []*model.Job
+	for _, name := range fs.Args() {
+		job, err := convertFile(name, *project, *buildID, p)
+		if err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+		jobs = append(jobs, job)
+	}
+
+	if *merge || singleJob[*format] && len(jobs) > 1 {
+		jobs = mergeJobs(jobs)
+	}
+	if singleJob[*format] && len(jobs) > 1 {
+		return fmt.Errorf("format %s holds a single job, but the files belong to %d jobs", *format, len(jobs))
+	}
+
+	w := io.Writer(os.Stdout)
+	if *output != "" {
+		file, err := os.Create(*output)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		w = file
+	}
+
+	for _, job := range jobs {
+		if err := write(w, job); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// formats are the output formats of convert, by name.
+var formats = map[string]func(io.Writer, *model.Job) error{
+	"json":  writeJSON,
+	"sarif": sarif.Write,
+	"junit": junit.Write,
+
+	"cobertura": coverage.WriteCobertura,
+	"lcov":      coverage.WriteLCOV,
+	"trace":     trace.Write,
+	"otlp":      otlp.Write,
+
+	"buildstats": buildstats.Write,
+}
+
+// singleJob are the formats whose documents cannot be concatenated. The files
+// are merged into one job for them.
+var singleJob = map[string]bool{
+	"sarif":     true,
+	"junit":     true,
+	"cobertura": true,
+	"trace":     true,
+	"otlp":      true,
+}
+
+func writeJSON(w io.Writer, job *model.Job) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(job)
+}
+
+func convertFile(name, project, buildID string, p *profile.Profile) (*model.Job, error) {
+	file, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var job *model.Job
+	switch filepath.Ext(name) {
+	case ".xml":
+		job, err = ctestxml.ParseProfile(file, project, p)
+	case ".tbz2", ".bz2":
+		job, err = gcovtar.ParseProfile(file, buildID, p)
+	default:
+		err = errors.New("unknown file type")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	job.ParserVersion = model.ParserVersion
+	return job, nil
+}
+
+// mergeJobs merges jobs with the same JobID, preserving the order in which
+// the IDs appear first. GcovTar files without an explicit ID are merged into
+// the preceding job.
+func mergeJobs(jobs []*model.Job) []*model.Job {
+	var merged []*model.Job
+	byID := map[string]*model.Job{}
+	for _, job := range jobs {
+		if job.JobID == "" && len(merged) != 0 {
+			job.JobID = merged[len(merged)-1].JobID
+		}
+		if dst, found := byID[job.JobID]; found {
+			aggregate.Merge(dst, job)
+			continue
+		}
+		dst := &model.Job{JobID: job.JobID}
+		aggregate.Merge(dst, job)
+		byID[job.JobID] = dst
+		merged = append(merged, dst)
+	}
+	return merged
+}
@@ -0,0 +1,110 @@
+// SPDX-FileCopyrightText: 2025 Daniel Pfeifer <daniel@pfeifer-mail.de>
+// SPDX-License-Identifier: ISC
+
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/chorse-dev/cdash-proxy/model"
+	"github.com/google/go-cmp/cmp"
+)
+
+var parts = []string{"Configure", "Build", "Test", "Done"}
+
+func testdata(name string) string {
+	return filepath.Join("ctestxml", "testdata", name)
+}
+
+// golden reads the expected result of parsing an XML file.
+func golden(t *testing.T, name string) *model.Job {
+	data, err := os.ReadFile(testdata(name + ".json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var job model.Job
+	if err := json.Unmarshal(data, &job); err != nil {
+		t.Fatal(err)
+	}
+	job.ParserVersion = model.ParserVersion
+	return &job
+}
+
+func runConvert(t *testing.T, args ...string) (string, error) {
+	output := filepath.Join(t.TempDir(), "output")
+	err := convert(append([]string{"-project", "Example", "-o", output}, args...))
+	data, _ := os.ReadFile(output)
+	return string(data), err
+}
+
+func TestMergeJobs(t *testing.T) {
+	var jobs []*model.Job
+	for _, name := range parts {
+		jobs = append(jobs, golden(t, name))
+	}
+	jobs = append(jobs, golden(t, "Upload"))
+
+	merged := mergeJobs(jobs)
+	if len(merged) != 2 {
+		t.Fatalf("expected 2 jobs, got %d", len(merged))
+	}
+
+	job := merged[0]
+	if job.JobID != jobs[0].JobID || !job.Done {
+		t.Errorf("unexpected job %s, done %v", job.JobID, job.Done)
+	}
+	if job.StartConfigureTime == nil || job.StartBuildTime == nil || job.StartTestTime == nil {
+		t.Error("expected the times of all parts")
+	}
+	commands := 0
+	for _, j := range jobs[:len(parts)] {
+		commands += len(j.Commands)
+	}
+	if len(job.Commands) != commands {
+		t.Errorf("expected %d commands, got %d", commands, len(job.Commands))
+	}
+	if merged[1].JobID != jobs[len(parts)].JobID {
+		t.Errorf("unexpected job %s", merged[1].JobID)
+	}
+}
+
+func TestConvertMerge(t *testing.T) {
+	var jobs []*model.Job
+	var files []string
+	for _, name := range parts {
+		jobs = append(jobs, golden(t, name))
+		files = append(files, testdata(name+".xml"))
+	}
+	expected := mergeJobs(jobs)
+
+	output, err := runConvert(t, append([]string{"-merge"}, files...)...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var actual model.Job
+	if err := json.Unmarshal([]byte(output), &actual); err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(expected[0], &actual); diff != "" {
+		t.Errorf("convert mismatch (-expected +actual):\n%s", diff)
+	}
+}
+
+func TestConvertSingleJob(t *testing.T) {
+	output, err := runConvert(t, "-format", "junit", testdata("Test.xml"), testdata("Done.xml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := strings.Count(output, "<testsuites"); n != 1 {
+		t.Errorf("expected one document, got %d", n)
+	}
+
+	_, err = runConvert(t, "-format", "sarif", testdata("Configure.xml"), testdata("Upload.xml"))
+	if err == nil || !strings.Contains(err.Error(), "2 jobs") {
+		t.Errorf("expected error for several jobs, got %v", err)
+	}
+}
@@ -0,0 +1,157 @@
+// SPDX-FileCopyrightText: 2025 Daniel Pfeifer <daniel@pfeifer-mail.de>
+// SPDX-License-Identifier: ISC
+
+package coverage
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"path"
+
+	"github.com/chorse-dev/cdash-proxy/model"
+)
+
+const coberturaDoctype = `<!DOCTYPE coverage SYSTEM "http://cobertura.sourceforge.net/xml/coverage-04.dtd">`
+
+type Cobertura struct {
+	XMLName         xml.Name  `xml:"coverage"`
+	LineRate        string    `xml:"line-rate,attr"`
+	BranchRate      string    `xml:"branch-rate,attr"`
+	LinesCovered    int       `xml:"lines-covered,attr"`
+	LinesValid      int       `xml:"lines-valid,attr"`
+	BranchesCovered int       `xml:"branches-covered,attr"`
+	BranchesValid   int       `xml:"branches-valid,attr"`
+	Complexity      string    `xml:"complexity,attr"`
+	Version         string    `xml:"version,attr"`
+	Timestamp       int64     `xml:"timestamp,attr"`
+	Packages        []Package `xml:"packages>package"`
+}
+
+type Package struct {
+	Name       string  `xml:"name,attr"`
+	LineRate   string  `xml:"line-rate,attr"`
+	BranchRate string  `xml:"branch-rate,attr"`
+	Complexity string  `xml:"complexity,attr"`
+	Classes    []Class `xml:"classes>class"`
+}
+
+type Class struct {
+	Name       string   `xml:"name,attr"`
+	Filename   string   `xml:"filename,attr"`
+	LineRate   string   `xml:"line-rate,attr"`
+	BranchRate string   `xml:"branch-rate,attr"`
+	Complexity string   `xml:"complexity,attr"`
+	Methods    struct{} `xml:"methods"`
+	Lines      Lines    `xml:"lines"`
+}
+
+type Lines struct {
+	Lines []Line `xml:"line"`
+}
+
+type Line struct {
+	Number            int    `xml:"number,attr"`
+	Hits              int    `xml:"hits,attr"`
+	Branch            bool   `xml:"branch,attr"`
+	ConditionCoverage string `xml:"condition-coverage,attr,omitempty"`
+}
+
+// ExportCobertura converts the coverage of a job. Files are grouped into
+// packages by their first label, files without label by their directory.
+func ExportCobertura(job *model.Job) *Cobertura {
+	report := &Cobertura{
+		Complexity: "0",
+		Version:    "cdash-proxy",
+		Packages:   []Package{},
+	}
+	if t := job.EndCoverageTime; t != nil {
+		report.Timestamp = t.UnixMilli()
+	}
+
+	var total counts
+	var pkgCounts []counts
+	index := map[string]int{}
+	for _, f := range files(job) {
+		name := f.label()
+		if name == "" {
+			name = path.Dir(f.FilePath)
+		}
+		i, found := index[name]
+		if !found {
+			i = len(report.Packages)
+			index[name] = i
+			report.Packages = append(report.Packages, Package{Name: name, Complexity: "0"})
+			pkgCounts = append(pkgCounts, counts{})
+		}
+
+		c := f.count()
+		pkgCounts[i].add(c)
+		total.add(c)
+		report.Packages[i].Classes = append(report.Packages[i].Classes, Class{
+			Name:       f.FilePath,
+			Filename:   f.FilePath,
+			LineRate:   rate(c.linesCovered, c.linesValid),
+			BranchRate: rate(c.branchesCovered, c.branchesValid),
+			Complexity: "0",
+			Lines:      Lines{Lines: f.coberturaLines()},
+		})
+	}
+
+	for i, c := range pkgCounts {
+		report.Packages[i].LineRate = rate(c.linesCovered, c.linesValid)
+		report.Packages[i].BranchRate = rate(c.branchesCovered, c.branchesValid)
+	}
+	report.LineRate = rate(total.linesCovered, total.linesValid)
+	report.BranchRate = rate(total.branchesCovered, total.branchesValid)
+	report.LinesCovered = total.linesCovered
+	report.LinesValid = total.linesValid
+	report.BranchesCovered = total.branchesCovered
+	report.BranchesValid = total.branchesValid
+	return report
+}
+
+// WriteCobertura writes the coverage of a job as Cobertura XML.
+func WriteCobertura(w io.Writer, job *model.Job) error {
+	if _, err := io.WriteString(w, xml.Header+coberturaDoctype+"\n"); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(ExportCobertura(job)); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+func (f *file) coberturaLines() []Line {
+	var lines []Line
+	for i, hits := range f.Lines {
+		if hits < 0 {
+			continue
+		}
+		line := Line{Number: i + 1, Hits: hits}
+		if branches := f.branches[i+1]; len(branches) != 0 {
+			covered := 0
+			for _, b := range branches {
+				if b == taken {
+					covered++
+				}
+			}
+			line.Branch = true
+			line.ConditionCoverage = fmt.Sprintf("%d%% (%d/%d)",
+				covered*100/len(branches), covered, len(branches))
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+// rate formats the covered fraction. Nothing to cover counts as covered.
+func rate(covered, valid int) string {
+	if valid == 0 {
+		return "1"
+	}
+	return fmt.Sprintf("%.4g", float64(covered)/float64(valid))
+}
@@ -0,0 +1,132 @@
+// SPDX-FileCopyrightText: 2025 Daniel Pfeifer <daniel@pfeifer-mail.de>
+// SPDX-License-Identifier: ISC
+
+// Package coverage exports the coverage of a job as Cobertura XML and as LCOV
+// tracefile.
+package coverage
+
+import (
+	"strings"
+
+	"github.com/chorse-dev/cdash-proxy/model"
+)
+
+// Branch states as reported by gcov.
+const (
+	notExecuted = -1
+	notTaken    = 0
+	taken       = 1
+)
+
+// file is the coverage of a file together with its branches by line.
+type file struct {
+	*model.Coverage
+	branches map[int][]int
+}
+
+// files returns the coverage of the job. Branches per line are only known
+// from the "Branch Coverage" diagnostics of GcovTar files.
+func files(job *model.Job) []file {
+	byPath := map[string]map[int][]int{}
+	for _, cmd := range job.Commands {
+		for _, d := range cmd.Diagnostics {
+			if d.Option != "Branch Coverage" || d.Line <= 0 {
+				continue
+			}
+			if byPath[d.FilePath] == nil {
+				byPath[d.FilePath] = map[int][]int{}
+			}
+			byPath[d.FilePath][d.Line] = append(byPath[d.FilePath][d.Line], parseBranches(d.Message)...)
+		}
+	}
+
+	result := make([]file, len(job.Coverage))
+	for i := range job.Coverage {
+		cov := &job.Coverage[i]
+		result[i] = file{Coverage: cov, branches: byPath[cov.FilePath]}
+	}
+	return result
+}
+
+// parseBranches parses lines like "branch  0 taken 50%".
+func parseBranches(text string) []int {
+	var branches []int
+	for _, line := range strings.Split(text, "\n") {
+		switch {
+		case !strings.HasPrefix(line, "branch"):
+		case strings.Contains(line, "never executed"):
+			branches = append(branches, notExecuted)
+		case strings.Contains(line, "taken 0%"):
+			branches = append(branches, notTaken)
+		default:
+			branches = append(branches, taken)
+		}
+	}
+	return branches
+}
+
+// counts are the valid and covered lines, branches and functions of a file.
+type counts struct {
+	linesValid, linesCovered         int
+	branchesValid, branchesCovered   int
+	functionsValid, functionsCovered int
+}
+
+func (c *counts) add(o counts) {
+	c.linesValid += o.linesValid
+	c.linesCovered += o.linesCovered
+	c.branchesValid += o.branchesValid
+	c.branchesCovered += o.branchesCovered
+	c.functionsValid += o.functionsValid
+	c.functionsCovered += o.functionsCovered
+}
+
+// count prefers the line hits and branches over the summary of the file.
+func (f *file) count() counts {
+	var c counts
+	if len(f.Lines) != 0 {
+		for _, hits := range f.Lines {
+			if hits >= 0 {
+				c.linesValid++
+			}
+			if hits > 0 {
+				c.linesCovered++
+			}
+		}
+	} else {
+		c.linesCovered = deref(f.LinesTested)
+		c.linesValid = c.linesCovered + deref(f.LinesUntested)
+	}
+
+	if len(f.branches) != 0 {
+		for _, branches := range f.branches {
+			for _, b := range branches {
+				c.branchesValid++
+				if b == taken {
+					c.branchesCovered++
+				}
+			}
+		}
+	} else {
+		c.branchesCovered = deref(f.BranchesTested)
+		c.branchesValid = c.branchesCovered + deref(f.BranchesUntested)
+	}
+
+	c.functionsCovered = deref(f.FunctionsTested)
+	c.functionsValid = c.functionsCovered + deref(f.FunctionsUntested)
+	return c
+}
+
+func (f *file) label() string {
+	if len(f.Labels) != 0 {
+		return f.Labels[0]
+	}
+	return ""
+}
+
+func deref(p *int) int {
+	if p == nil {
+		return 0
+	}
+	return *p
+}
@@ -0,0 +1,103 @@
+// SPDX-FileCopyrightText: 2025 Daniel Pfeifer <daniel@pfeifer-mail.de>
+// SPDX-License-Identifier: ISC
+
+package coverage
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/chorse-dev/cdash-proxy/model"
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+)
+
+func testJob() *model.Job {
+	ptr := func(i int) *int { return &i }
+	return &model.Job{
+		Commands: []model.Command{{Diagnostics: []model.Diagnostic{{
+			FilePath: "src/a.c",
+			Line:     2,
+			Type:     "Warning",
+			Message:  "branch  0 taken 100%\nbranch  1 taken 0%\n",
+			Option:   "Branch Coverage",
+		}, {
+			FilePath: "src/a.c",
+			Line:     3,
+			Type:     "Warning",
+			Message:  "branch  0 never executed\nbranch  1 never executed\n",
+			Option:   "Branch Coverage",
+		}}}},
+		Coverage: []model.Coverage{
+			{FilePath: "src/a.c", Lines: []int{-1, 4, 0, 2}, Labels: []string{"core lib"}},
+			{FilePath: "src/b.c", LinesTested: ptr(3), LinesUntested: ptr(1),
+				BranchesTested: ptr(1), BranchesUntested: ptr(1),
+				FunctionsTested: ptr(1), FunctionsUntested: ptr(0)},
+		},
+	}
+}
+
+func TestLCOV(t *testing.T) {
+	var buf strings.Builder
+	if err := WriteLCOV(&buf, testJob()); err != nil {
+		t.Fatal(err)
+	}
+
+	want := `TN:core_lib
+SF:src/a.c
+BRDA:2,0,0,1
+BRDA:2,0,1,0
+BRDA:3,0,0,-
+BRDA:3,0,1,-
+BRF:4
+BRH:1
+DA:2,4
+DA:3,0
+DA:4,2
+LF:3
+LH:2
+end_of_record
+TN:
+SF:src/b.c
+FNF:1
+FNH:1
+BRF:2
+BRH:1
+LF:4
+LH:3
+end_of_record
+`
+	if diff := cmp.Diff(want, buf.String()); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestCobertura(t *testing.T) {
+	want := &Cobertura{
+		LineRate:        "0.7143",
+		BranchRate:      "0.3333",
+		LinesCovered:    5,
+		LinesValid:      7,
+		BranchesCovered: 2,
+		BranchesValid:   6,
+		Complexity:      "0",
+		Version:         "cdash-proxy",
+		Packages: []Package{
+			{Name: "core lib", LineRate: "0.6667", BranchRate: "0.25", Complexity: "0", Classes: []Class{{
+				Name: "src/a.c", Filename: "src/a.c", LineRate: "0.6667", BranchRate: "0.25", Complexity: "0",
+				Lines: Lines{Lines: []Line{
+					{Number: 2, Hits: 4, Branch: true, ConditionCoverage: "50% (1/2)"},
+					{Number: 3, Hits: 0, Branch: true, ConditionCoverage: "0% (0/2)"},
+					{Number: 4, Hits: 2},
+				}},
+			}}},
+			{Name: "src", LineRate: "0.75", BranchRate: "0.5", Complexity: "0", Classes: []Class{{
+				Name: "src/b.c", Filename: "src/b.c", LineRate: "0.75", BranchRate: "0.5", Complexity: "0",
+			}}},
+		},
+	}
+
+	if diff := cmp.Diff(want, ExportCobertura(testJob()), cmpopts.IgnoreFields(Cobertura{}, "XMLName")); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+}
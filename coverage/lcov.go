@@ -0,0 +1,55 @@
+// SPDX-FileCopyrightText: 2025 Daniel Pfeifer <daniel@pfeifer-mail.de>
+// SPDX-License-Identifier: ISC
+
+package coverage
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"maps"
+	"regexp"
+	"slices"
+
+	"github.com/chorse-dev/cdash-proxy/model"
+)
+
+var reNonWord = regexp.MustCompile(`\W`)
+
+// WriteLCOV writes the coverage of a job as LCOV tracefile. The test name of
+// each file is its first label. Branches that gcov reports as taken are
+// counted once, since the number of times is not known.
+func WriteLCOV(w io.Writer, job *model.Job) error {
+	bw := bufio.NewWriter(w)
+	for _, f := range files(job) {
+		c := f.count()
+		fmt.Fprintf(bw, "TN:%s\n", reNonWord.ReplaceAllString(f.label(), "_"))
+		fmt.Fprintf(bw, "SF:%s\n", f.FilePath)
+
+		if f.FunctionsTested != nil || f.FunctionsUntested != nil {
+			fmt.Fprintf(bw, "FNF:%d\nFNH:%d\n", c.functionsValid, c.functionsCovered)
+		}
+
+		for _, line := range slices.Sorted(maps.Keys(f.branches)) {
+			for i, b := range f.branches[line] {
+				count := "-"
+				if b != notExecuted {
+					count = fmt.Sprint(b)
+				}
+				fmt.Fprintf(bw, "BRDA:%d,0,%d,%s\n", line, i, count)
+			}
+		}
+		if c.branchesValid != 0 {
+			fmt.Fprintf(bw, "BRF:%d\nBRH:%d\n", c.branchesValid, c.branchesCovered)
+		}
+
+		for i, hits := range f.Lines {
+			if hits >= 0 {
+				fmt.Fprintf(bw, "DA:%d,%d\n", i+1, hits)
+			}
+		}
+		fmt.Fprintf(bw, "LF:%d\nLH:%d\n", c.linesValid, c.linesCovered)
+		fmt.Fprintln(bw, "end_of_record")
+	}
+	return bw.Flush()
+}
@@ -6,15 +6,18 @@ package ctestxml
 import (
 	"bytes"
 	"path/filepath"
+	"slices"
 	"strings"
 	"time"
 
 	"github.com/chorse-dev/cdash-proxy/algorithm"
 	"github.com/chorse-dev/cdash-proxy/ctestxml/buildparser"
+	"github.com/chorse-dev/cdash-proxy/fingerprint"
 	"github.com/chorse-dev/cdash-proxy/model"
+	"github.com/chorse-dev/cdash-proxy/profile"
 )
 
-func parseBuild(build *Build) TimedCommands {
+func parseBuild(build *Build, p *profile.Profile) TimedCommands {
 	startTime := time.Unix(build.StartBuildTime, 0)
 	endTime := time.Unix(build.EndBuildTime, 0)
 	var cmds []model.Command
@@ -48,11 +51,14 @@ func parseBuild(build *Build) TimedCommands {
 	}
 
 	cmds[0].StdOut = combineOutput(build.Diagnostics)
-	cmds[0].Diagnostics = mapDiagnostics(build.Diagnostics)
+	cmds[0].Diagnostics = mapDiagnostics(build.Diagnostics, p)
 
 	for _, target := range build.Targets {
 		for _, command := range target.Commands.Commands {
-			source := stripSourcePath(command.Source, build.BinaryDirectory, build.SourceDirectory)
+			source := command.Source
+			if p.StripSourcePaths() {
+				source = stripSourcePath(source, build.BinaryDirectory, build.SourceDirectory)
+			}
 			cmd := model.Command{
 				Role:             command.Role(),
 				Result:           command.Result,
@@ -83,7 +89,7 @@ func parseBuild(build *Build) TimedCommands {
 		commandLine := failure.CommandLine()
 		stdout := failure.CleanStdOut()
 		stderr := failure.CleanStdErr()
-		diagnostics := failure.Diagnostics()
+		diagnostics := failure.Diagnostics(p)
 
 		if cmd, found := lookupTable[commandLine]; found {
 			cmd.StdOut = stdout
@@ -128,7 +134,11 @@ func combineOutput(messages []Diagnostic) string {
 	return buffer.String()
 }
 
-func mapDiagnostics(messages []Diagnostic) []model.Diagnostic {
+func mapDiagnostics(messages []Diagnostic, p *profile.Profile) []model.Diagnostic {
+	rules := p.Rules()
+	messages = slices.DeleteFunc(slices.Clone(messages), func(e Diagnostic) bool {
+		return rules.Excepted(e.XMLName.Local, e.Text)
+	})
 	return algorithm.Map(messages, func(e Diagnostic) model.Diagnostic {
 		diag := model.Diagnostic{
 			FilePath: e.SourceFile,
@@ -137,11 +147,16 @@ func mapDiagnostics(messages []Diagnostic) []model.Diagnostic {
 			Type:     e.XMLName.Local,
 			Message:  e.Text,
 		}
-		if opt := buildparser.ParseDiagnostic(e.Text); opt != nil {
+		if opt := rules.ParseDiagnostic(e.Text); opt != nil {
 			diag.Column = opt.Column
 			diag.Message = opt.Message
 			diag.Option = opt.Option
+			if diag.FilePath == "" {
+				diag.FilePath = opt.FilePath
+				diag.Line = opt.Line
+			}
 		}
+		diag.Fingerprint = fingerprint.Diagnostic(&diag, buildparser.CodeLine(e.PostContext))
 		return diag
 	})
 }
@@ -6,7 +6,9 @@ package buildparser
 import (
 	"path/filepath"
 	"regexp"
+	"slices"
 	"strconv"
+	"strings"
 
 	"github.com/chorse-dev/cdash-proxy/algorithm"
 	"github.com/chorse-dev/cdash-proxy/model"
@@ -26,6 +28,16 @@ var reFileLine = algorithm.Map([]string{
 })
 
 func ParseDiagnostic(line string) *model.Diagnostic {
+	return ParseDiagnosticWith(line, nil)
+}
+
+// ParseDiagnosticWith tries the given patterns before the built-in ones.
+func ParseDiagnosticWith(line string, patterns []*regexp.Regexp) *model.Diagnostic {
+	for _, re := range patterns {
+		if match := re.FindStringSubmatch(line); match != nil {
+			return toDiagnostic(re, match)
+		}
+	}
 	for _, re := range reFileLine {
 		if match := re.FindStringSubmatch(line); match != nil {
 			return toDiagnostic(re, match)
@@ -34,6 +46,104 @@ func ParseDiagnostic(line string) *model.Diagnostic {
 	return nil
 }
 
+var reCodeLine = regexp.MustCompile(`^\s*[0-9]+ \| (.*)$`)
+
+// CodeLine returns the line of code that GCC prints below a diagnostic, or an
+// empty string if text does not start with one.
+func CodeLine(text string) string {
+	line, _, _ := strings.Cut(text, "\n")
+	if match := reCodeLine.FindStringSubmatch(line); match != nil {
+		return match[1]
+	}
+	return ""
+}
+
+// Rules extend the built-in patterns. They mirror CTest's
+// CTEST_CUSTOM_ERROR_MATCH, CTEST_CUSTOM_ERROR_EXCEPTION,
+// CTEST_CUSTOM_WARNING_MATCH and CTEST_CUSTOM_WARNING_EXCEPTION. A nil Rules
+// uses only the built-in patterns.
+type Rules struct {
+	// Patterns are tried before the built-in patterns.
+	Patterns []*regexp.Regexp
+
+	ErrorMatch       []*regexp.Regexp
+	ErrorException   []*regexp.Regexp
+	WarningMatch     []*regexp.Regexp
+	WarningException []*regexp.Regexp
+}
+
+// ParseDiagnostic tries the match rules first, then the patterns. Lines that
+// match an exception for the type of the diagnostic are not diagnostics.
+func (r *Rules) ParseDiagnostic(line string) *model.Diagnostic {
+	if r == nil {
+		return ParseDiagnostic(line)
+	}
+
+	for _, c := range []struct {
+		typ   string
+		match []*regexp.Regexp
+	}{
+		{"Error", r.ErrorMatch},
+		{"Warning", r.WarningMatch},
+	} {
+		if r.Excepted(c.typ, line) {
+			continue
+		}
+		for _, re := range c.match {
+			if match := re.FindStringSubmatch(line); match != nil {
+				return toCustomDiagnostic(re, match, c.typ, line)
+			}
+		}
+	}
+
+	diag := ParseDiagnosticWith(line, r.Patterns)
+	if diag == nil || r.Excepted(diag.Type, line) {
+		return nil
+	}
+	return diag
+}
+
+// Excepted reports whether the line matches an exception for diagnostics of
+// the given type. Diagnostics without type are checked against both.
+func (r *Rules) Excepted(typ, line string) bool {
+	if r == nil {
+		return false
+	}
+
+	matches := func(re *regexp.Regexp) bool {
+		return re.MatchString(line)
+	}
+	switch typ {
+	case "Error":
+		return slices.ContainsFunc(r.ErrorException, matches)
+	case "Warning":
+		return slices.ContainsFunc(r.WarningException, matches)
+	case "":
+		return slices.ContainsFunc(r.ErrorException, matches) ||
+			slices.ContainsFunc(r.WarningException, matches)
+	}
+	return false
+}
+
+// toCustomDiagnostic converts the match of a custom rule. Fields without a
+// named group fall back to the type of the rule and the whole line.
+func toCustomDiagnostic(re *regexp.Regexp, match []string, typ, line string) *model.Diagnostic {
+	diag := toDiagnostic(re, match)
+	if re.SubexpIndex("type") == -1 {
+		diag.Type = typ
+	}
+	if re.SubexpIndex("message") == -1 {
+		diag.Message = line
+	}
+	if re.SubexpIndex("line") == -1 {
+		diag.Line = -1
+	}
+	if re.SubexpIndex("column") == -1 {
+		diag.Column = -1
+	}
+	return diag
+}
+
 func toDiagnostic(re *regexp.Regexp, match []string) *model.Diagnostic {
 	diag := &model.Diagnostic{}
 	for k, name := range re.SubexpNames() {
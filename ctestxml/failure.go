@@ -10,7 +10,9 @@ import (
 
 	"github.com/chorse-dev/cdash-proxy/algorithm"
 	"github.com/chorse-dev/cdash-proxy/ctestxml/buildparser"
+	"github.com/chorse-dev/cdash-proxy/fingerprint"
 	"github.com/chorse-dev/cdash-proxy/model"
+	"github.com/chorse-dev/cdash-proxy/profile"
 )
 
 var failure_log_replacer = strings.NewReplacer(
@@ -37,11 +39,18 @@ func (f *Failure) CommandLine() string {
 	return strings.Join(args, " ")
 }
 
-func (f *Failure) Diagnostics() []model.Diagnostic {
+func (f *Failure) Diagnostics(p *profile.Profile) []model.Diagnostic {
 	var diags []model.Diagnostic
-	for _, line := range strings.Split(f.CleanStdErr(), "\n") {
-		if opt := buildparser.ParseDiagnostic(line); opt != nil {
+	var contexts []string
+	lines := strings.Split(f.CleanStdErr(), "\n")
+	for i, line := range lines {
+		if opt := p.Rules().ParseDiagnostic(line); opt != nil {
 			diags = append(diags, *opt)
+			var context string
+			if i+1 < len(lines) {
+				context = buildparser.CodeLine(lines[i+1])
+			}
+			contexts = append(contexts, context)
 		}
 	}
 
@@ -52,14 +61,28 @@ func (f *Failure) Diagnostics() []model.Diagnostic {
 			Type:    "Error",
 			Message: fmt.Sprintf("Command finished with exit code %d", f.ExitCondition),
 		})
+		contexts = append(contexts, "")
 	}
 
+	if p.StripSourcePaths() {
+		f.stripPrefix(diags)
+	}
+
+	for i := range diags {
+		diags[i].Fingerprint = fingerprint.Diagnostic(&diags[i], contexts[i])
+	}
+	return diags
+}
+
+// stripPrefix makes the paths relative to the source directory, which is
+// derived from the path of the source file.
+func (f *Failure) stripPrefix(diags []model.Diagnostic) {
 	// 1. Loop over all diags, find an elem where elem.FilePath ends with file.
 	elem := algorithm.FindIf(diags, func(d model.Diagnostic) bool {
 		return strings.HasSuffix(d.FilePath, f.SourceFile)
 	})
 	if elem == nil {
-		return diags
+		return
 	}
 
 	// 2. calculate the prefix
@@ -69,6 +92,4 @@ func (f *Failure) Diagnostics() []model.Diagnostic {
 	for idx, diag := range diags {
 		diags[idx].FilePath = strings.TrimPrefix(diag.FilePath, prefix)
 	}
-
-	return diags
 }
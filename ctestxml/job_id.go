@@ -6,7 +6,10 @@ package ctestxml
 import (
 	"crypto/md5"
 	"encoding/hex"
+	"encoding/xml"
+	"errors"
 	"fmt"
+	"io"
 )
 
 func GenerateJobID(project, site, stamp, build string) string {
@@ -14,3 +17,73 @@ func GenerateJobID(project, site, stamp, build string) string {
 	fmt.Fprintf(hasher, "%s-%s-%s-%s", project, site, stamp, build)
 	return hex.EncodeToString(hasher.Sum(nil))
 }
+
+// Header holds the identifying information of a CTest XML file.
+type Header struct {
+	JobID string
+	Site  string
+}
+
+// PeekJobID determines the JobID of a CTest XML file without parsing all of
+// it. It stops reading as soon as the relevant elements were found.
+func PeekJobID(r io.Reader, project string) (string, error) {
+	h, err := Peek(r, project)
+	if err != nil {
+		return "", err
+	}
+	return h.JobID, nil
+}
+
+// Peek reads the header of a CTest XML file. The site of Done.xml is unknown.
+func Peek(r io.Reader, project string) (*Header, error) {
+	dec := xml.NewDecoder(r)
+	se, err := startElement(dec)
+	if err != nil {
+		return nil, err
+	}
+
+	if se.Name.Local == "Site" {
+		var site, stamp, build string
+		for _, attr := range se.Attr {
+			switch attr.Name.Local {
+			case "Name":
+				site = attr.Value
+			case "BuildStamp":
+				stamp = attr.Value
+			case "BuildName":
+				build = attr.Value
+			}
+		}
+		return &Header{GenerateJobID(project, site, stamp, build), site}, nil
+	}
+
+	if se.Name.Local != "Update" && se.Name.Local != "Done" {
+		return nil, errors.New("Unknown XML Tag " + se.Name.Local)
+	}
+
+	fields := map[string]string{}
+	for {
+		child, err := startElement(dec)
+		if err != nil {
+			return nil, err
+		}
+
+		var value string
+		if err := dec.DecodeElement(&value, child); err != nil {
+			return nil, err
+		}
+		fields[child.Name.Local] = value
+
+		if se.Name.Local == "Done" && child.Name.Local == "buildId" {
+			return &Header{JobID: value}, nil
+		}
+
+		_, hasSite := fields["Site"]
+		_, hasStamp := fields["BuildStamp"]
+		_, hasBuild := fields["BuildName"]
+		if hasSite && hasStamp && hasBuild {
+			jobID := GenerateJobID(project, fields["Site"], fields["BuildStamp"], fields["BuildName"])
+			return &Header{jobID, fields["Site"]}, nil
+		}
+	}
+}
@@ -0,0 +1,39 @@
+// SPDX-FileCopyrightText: 2025 Daniel Pfeifer <daniel@pfeifer-mail.de>
+// SPDX-License-Identifier: ISC
+
+package ctestxml
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestPeekJobID(t *testing.T) {
+	xmlFiles, err := filepath.Glob("testdata/*.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, xmlFile := range xmlFiles {
+		file, err := os.Open(xmlFile)
+		if err != nil {
+			t.Fatal(err)
+		}
+		job, err := Parse(file, "Example")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		file.Seek(0, 0)
+		jobID, err := PeekJobID(file, "Example")
+		file.Close()
+		if err != nil {
+			t.Errorf("%s: %v", xmlFile, err)
+			continue
+		}
+		if jobID != job.JobID {
+			t.Errorf("%s: expected %s, got %s", xmlFile, job.JobID, jobID)
+		}
+	}
+}
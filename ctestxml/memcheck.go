@@ -9,25 +9,31 @@ import (
 	"github.com/chorse-dev/cdash-proxy/algorithm"
 	"github.com/chorse-dev/cdash-proxy/ctestxml/memcheck"
 	"github.com/chorse-dev/cdash-proxy/model"
+	"github.com/chorse-dev/cdash-proxy/profile"
 )
 
-func parseDynamicAnalysis(da *DynamicAnalysis) TimedCommands {
+func parseDynamicAnalysis(da *DynamicAnalysis, p *profile.Profile) TimedCommands {
 	return TimedCommands{
 		StartTime: time.Unix(da.StartTime, 0),
 		EndTime:   time.Unix(da.EndTime, 0),
-		Commands:  transformTestsDA(da),
+		Commands:  transformTestsDA(da, p),
 	}
 }
 
-func transformTestsDA(da *DynamicAnalysis) []model.Command {
+func transformTestsDA(da *DynamicAnalysis, p *profile.Profile) []model.Command {
+	enabled := p.MemcheckEnabled(da.Checker)
 	return algorithm.Map(da.Tests, func(t DynamicAnalysisTest) model.Command {
+		diags := []model.Diagnostic{}
+		if enabled {
+			diags = memcheck.Parse(da.Checker, t.Log.string)
+		}
 		return model.Command{
 			TestName:     t.Name,
 			Role:         "test",
 			TestStatus:   t.Status,
 			CommandLine:  t.CommandLine,
 			StdOut:       t.Log.string,
-			Diagnostics:  memcheck.Parse(da.Checker, t.Log.string),
+			Diagnostics:  diags,
 			Attributes:   map[string]string{"DA Checker": da.Checker},
 			Measurements: memcheckParseDefects(t.Defects)}
 	})
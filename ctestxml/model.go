@@ -17,21 +17,31 @@ type Done struct {
 }
 
 type Update struct {
-	Mode       string       `xml:"mode,attr"`
-	Generator  string       `xml:"Generator,attr"`
-	Site       string       `xml:"Site"`
-	BuildName  string       `xml:"BuildName"`
-	BuildStamp string       `xml:"BuildStamp"`
-	StartTime  int64        `xml:"StartTime"`
-	EndTime    int64        `xml:"EndTime"`
-	Command    string       `xml:"UpdateCommand"`
-	Type       string       `xml:"UpdateType"`
-	Revision   string       `xml:"Revision"`
-	Status     string       `xml:"UpdateReturnStatus"`
-	Files      []UpdateFile `xml:"Directory>Updated"`
+	Mode          string            `xml:"mode,attr"`
+	Generator     string            `xml:"Generator,attr"`
+	Site          string            `xml:"Site"`
+	BuildName     string            `xml:"BuildName"`
+	BuildStamp    string            `xml:"BuildStamp"`
+	StartTime     int64             `xml:"StartTime"`
+	EndTime       int64             `xml:"EndTime"`
+	Command       string            `xml:"UpdateCommand"`
+	Type          string            `xml:"UpdateType"`
+	Revision      string            `xml:"Revision"`
+	PriorRevision string            `xml:"PriorRevision"`
+	Status        string            `xml:"UpdateReturnStatus"`
+	Directories   []UpdateDirectory `xml:"Directory"`
+}
+
+type UpdateDirectory struct {
+	Name        string       `xml:"Name"`
+	Updated     []UpdateFile `xml:"Updated"`
+	Modified    []UpdateFile `xml:"Modified"`
+	Conflicting []UpdateFile `xml:"Conflicting"`
 }
 
 type UpdateFile struct {
+	File           string `xml:"File"`
+	Directory      string `xml:"Directory"`
 	FullName       string `xml:"FullName"`
 	Author         string `xml:"Author"`
 	Email          string `xml:"Email"`
@@ -41,6 +51,7 @@ type UpdateFile struct {
 	CommitDate     string `xml:"CommitDate"`
 	Log            string `xml:"Log"`
 	Revision       string `xml:"Revision"`
+	PriorRevision  string `xml:"PriorRevision"`
 }
 
 type Site struct {
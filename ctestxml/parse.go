@@ -8,24 +8,46 @@ import (
 	"errors"
 	"io"
 
+	"github.com/chorse-dev/cdash-proxy/fingerprint"
 	"github.com/chorse-dev/cdash-proxy/model"
+	"github.com/chorse-dev/cdash-proxy/profile"
 )
 
 func Parse(r io.Reader, project string) (*model.Job, error) {
+	return ParseProfile(r, project, nil)
+}
+
+// ParseProfile parses the XML file with the processing settings of the
+// project.
+func ParseProfile(r io.Reader, project string, p *profile.Profile) (*model.Job, error) {
 	dec := xml.NewDecoder(r)
 	se, err := startElement(dec)
 	if err != nil {
 		return nil, err
 	}
+
+	var job *model.Job
 	switch se.Name.Local {
 	case "Done":
-		return parseDone(dec, se, project)
+		job, err = parseDone(dec, se, project)
 	case "Site":
-		return parseSite(dec, se, project)
+		job, err = parseSite(dec, se, project, p)
 	case "Update":
-		return parseUpdate(dec, se, project)
+		job, err = parseUpdate(dec, se, project)
+	default:
+		err = errors.New("Unknown XML Tag " + se.Name.Local)
 	}
-	return nil, errors.New("Unknown XML Tag " + se.Name.Local)
+	if err != nil {
+		return nil, err
+	}
+
+	parseSARIF(job, p)
+	job.Coverage = p.FilterCoverage(job.Coverage)
+	fingerprint.Job(job)
+	fingerprint.Dedupe(job)
+	p.SuppressDiagnostics(job)
+	p.LimitAttachments(job)
+	return job, nil
 }
 
 func startElement(dec *xml.Decoder) (*xml.StartElement, error) {
@@ -0,0 +1,109 @@
+// SPDX-FileCopyrightText: 2025 Daniel Pfeifer <daniel@pfeifer-mail.de>
+// SPDX-License-Identifier: ISC
+
+package ctestxml
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/chorse-dev/cdash-proxy/model"
+	"github.com/chorse-dev/cdash-proxy/profile"
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+)
+
+// ignoreFingerprint lets tests of the extraction ignore the fingerprints.
+var ignoreFingerprint = cmpopts.IgnoreFields(model.Diagnostic{}, "Fingerprint")
+
+func parseWithProfile(t *testing.T, name string, p *profile.Profile) *model.Job {
+	t.Helper()
+	if err := p.Compile(); err != nil {
+		t.Fatal(err)
+	}
+	file, err := os.Open(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	job, err := ParseProfile(file, "Example", p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return job
+}
+
+func TestProfileStripSourcePath(t *testing.T) {
+	job := parseWithProfile(t, "BuildIL.xml", &profile.Profile{StripSourcePath: new(bool)})
+	for _, cmd := range job.Commands {
+		if cmd.Source != "" && !filepath.IsAbs(cmd.Source) {
+			t.Errorf("expected absolute source path, got %s", cmd.Source)
+		}
+	}
+}
+
+func TestProfileMemcheck(t *testing.T) {
+	job := parseWithProfile(t, "DynamicAnalysis.xml", &profile.Profile{Memcheck: []string{}})
+	for _, cmd := range job.Commands {
+		if len(cmd.Diagnostics) != 0 {
+			t.Errorf("%s: expected no diagnostics, got %v", cmd.TestName, cmd.Diagnostics)
+		}
+	}
+}
+
+func TestProfileCoverage(t *testing.T) {
+	p := &profile.Profile{}
+	p.Coverage.Exclude = []string{"**/asan.c"}
+	job := parseWithProfile(t, "Coverage.xml", p)
+	if len(job.Coverage) == 0 {
+		t.Fatal("expected coverage")
+	}
+	for _, c := range job.Coverage {
+		if strings.HasSuffix(c.FilePath, "/asan.c") {
+			t.Errorf("expected %s to be excluded", c.FilePath)
+		}
+	}
+}
+
+func TestProfileWarningException(t *testing.T) {
+	p := &profile.Profile{WarningException: []string{`\[-Wunused-variable\]`}}
+	job := parseWithProfile(t, "Build.xml", p)
+	for _, diag := range job.Commands[0].Diagnostics {
+		if diag.Option == "-Wunused-variable" {
+			t.Errorf("expected %v to be excepted", diag)
+		}
+	}
+	if n := len(job.Commands[0].Diagnostics); n != 2 {
+		t.Errorf("expected 2 diagnostics, got %d", n)
+	}
+}
+
+func TestProfileErrorMatch(t *testing.T) {
+	p := &profile.Profile{
+		ErrorMatch:       []string{`^ERROR\[(?P<option>E[0-9]+)\] (?P<file>\S+) at line (?P<line>[0-9]+): (?P<message>.*)$`},
+		WarningMatch:     []string{`^NOTICE: `},
+		WarningException: []string{`deprecated`},
+	}
+	if err := p.Compile(); err != nil {
+		t.Fatal(err)
+	}
+
+	failure := &Failure{
+		SourceFile: "src/solver.f90",
+		StdErr: "ERROR[E042] /source/src/solver.f90 at line 12: undefined symbol x\n" +
+			"NOTICE: stack size exceeds 1 MB\n" +
+			"NOTICE: flag -O4 is deprecated\n" +
+			"/source/src/solver.f90:3:1: warning: unused label [-Wunused-label]\n",
+		ExitCondition: 1,
+	}
+	want := []model.Diagnostic{
+		{FilePath: "src/solver.f90", Line: 12, Column: -1, Type: "Error", Message: "undefined symbol x", Option: "E042"},
+		{FilePath: "", Line: -1, Column: -1, Type: "Warning", Message: "NOTICE: stack size exceeds 1 MB"},
+		{FilePath: "src/solver.f90", Line: 3, Column: 1, Type: "Warning", Message: "unused label", Option: "-Wunused-label"},
+	}
+	if diff := cmp.Diff(want, failure.Diagnostics(p), ignoreFingerprint); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+}
@@ -0,0 +1,78 @@
+// SPDX-FileCopyrightText: 2025 Daniel Pfeifer <daniel@pfeifer-mail.de>
+// SPDX-License-Identifier: ISC
+
+package ctestxml
+
+import (
+	"bytes"
+	"log"
+	"strings"
+
+	"github.com/chorse-dev/cdash-proxy/model"
+	"github.com/chorse-dev/cdash-proxy/profile"
+	"github.com/chorse-dev/cdash-proxy/sarif"
+)
+
+// parseSARIF replaces the diagnostics that were scraped from the output of a
+// command with those of the SARIF files attached to it. SARIF files attached
+// to the job, like with ctest_upload(), become commands of their own: the
+// runs of CMake configure commands, all other runs compile commands of the
+// analyzed source. The aggregator merges them with the commands that they
+// belong to.
+func parseSARIF(job *model.Job, p *profile.Profile) {
+	strip := p.StripSourcePaths()
+
+	for i := range job.Commands {
+		cmd := &job.Commands[i]
+		var diags []model.Diagnostic
+		found := false
+		for _, file := range cmd.AttachedFiles {
+			analyses := importSARIF(&file, strip)
+			if analyses == nil {
+				continue
+			}
+			found = true
+			for _, a := range analyses {
+				diags = append(diags, a.Diagnostics...)
+			}
+		}
+		if found {
+			cmd.Diagnostics = diags
+		}
+	}
+
+	for _, file := range job.AttachedFiles {
+		for _, a := range importSARIF(&file, strip) {
+			cmd := model.Command{
+				CommandLine: a.CommandLine,
+				Role:        "compile",
+				Diagnostics: a.Diagnostics,
+				Attributes:  map[string]string{sarif.Attribute: file.Name},
+			}
+			if strings.EqualFold(a.Tool, "CMake") {
+				cmd.Role = "configure"
+			} else if len(a.Targets) != 0 {
+				cmd.Source = a.Targets[0]
+			}
+			job.Commands = append(job.Commands, cmd)
+		}
+	}
+}
+
+// importSARIF returns nil if the file is no valid SARIF log.
+func importSARIF(file *model.AttachedFile, strip bool) []sarif.Analysis {
+	if !isSARIF(file.Filename) {
+		return nil
+	}
+	l, err := sarif.Parse(bytes.NewReader(file.Content))
+	if err != nil {
+		log.Printf("%s: %v\n", file.Name, err)
+		return nil
+	}
+	return l.Import(strip)
+}
+
+func isSARIF(filename string) bool {
+	filename = strings.ToLower(filename)
+	return strings.HasSuffix(filename, ".sarif") || strings.HasSuffix(filename, ".sarif.json")
+}
@@ -0,0 +1,39 @@
+// SPDX-FileCopyrightText: 2025 Daniel Pfeifer <daniel@pfeifer-mail.de>
+// SPDX-License-Identifier: ISC
+
+package ctestxml
+
+import (
+	"testing"
+
+	"github.com/chorse-dev/cdash-proxy/model"
+)
+
+func TestSARIFAttachedToTest(t *testing.T) {
+	log := `{"version": "2.1.0", "runs": [{"tool": {"driver": {"name": "clang-tidy"}}, "results": [
+		{"ruleId": "bugprone-use-after-move", "level": "warning", "message": {"text": "'v' used after it was moved"},
+		 "locations": [{"physicalLocation": {"artifactLocation": {"uri": "src/a.cpp"}, "region": {"startLine": 4, "startColumn": 3}}}]}
+	]}]}`
+	job := &model.Job{Commands: []model.Command{{
+		Role:        "test",
+		TestName:    "tidy",
+		Diagnostics: []model.Diagnostic{{Type: "Warning", Message: "scraped from the output"}},
+		AttachedFiles: []model.AttachedFile{
+			{Name: "log", Filename: "tidy.log", Content: []byte("not SARIF")},
+			{Name: "tidy", Filename: "tidy.sarif", Content: []byte(log)},
+		},
+	}}}
+
+	parseSARIF(job, nil)
+
+	diags := job.Commands[0].Diagnostics
+	if len(diags) != 1 {
+		t.Fatalf("expected 1 diagnostic, got %v", diags)
+	}
+	if d := diags[0]; d.FilePath != "src/a.cpp" || d.Line != 4 || d.Option != "bugprone-use-after-move" {
+		t.Errorf("unexpected diagnostic %+v", d)
+	}
+	if len(job.Commands) != 1 {
+		t.Errorf("expected no additional commands, got %d", len(job.Commands))
+	}
+}
@@ -10,6 +10,7 @@ import (
 	"time"
 
 	"github.com/chorse-dev/cdash-proxy/model"
+	"github.com/chorse-dev/cdash-proxy/profile"
 )
 
 type TimedCommands struct {
@@ -24,7 +25,7 @@ type TimedCovarage struct {
 	EndTime   time.Time
 }
 
-func parseSite(dec *xml.Decoder, elem *xml.StartElement, project string) (*model.Job, error) {
+func parseSite(dec *xml.Decoder, elem *xml.StartElement, project string, p *profile.Profile) (*model.Job, error) {
 	var site Site
 	if err := dec.DecodeElement(&site, elem); err != nil {
 		return nil, err
@@ -79,13 +80,13 @@ func parseSite(dec *xml.Decoder, elem *xml.StartElement, project string) (*model
 		job.EndConfigureTime = &ret.EndTime
 	}
 	if site.Build != nil {
-		ret := parseBuild(site.Build)
+		ret := parseBuild(site.Build, p)
 		job.Commands = ret.Commands
 		job.StartBuildTime = &ret.StartTime
 		job.EndBuildTime = &ret.EndTime
 	}
 	if site.Testing != nil {
-		ret := parseTesting(site.Testing, site.Subprojects)
+		ret := parseTesting(site.Testing, site.Subprojects, p)
 		job.Commands = ret.Commands
 		job.StartTestTime = &ret.StartTime
 		job.EndTestTime = &ret.EndTime
@@ -103,7 +104,7 @@ func parseSite(dec *xml.Decoder, elem *xml.StartElement, project string) (*model
 		job.EndCoverageTime = &ret.EndTime
 	}
 	if site.DynamicAnalysis != nil {
-		ret := parseDynamicAnalysis(site.DynamicAnalysis)
+		ret := parseDynamicAnalysis(site.DynamicAnalysis, p)
 		job.Commands = ret.Commands
 		job.StartMemcheckTime = &ret.StartTime
 		job.EndMemcheckTime = &ret.EndTime
@@ -4,21 +4,24 @@
 package ctestxml
 
 import (
+	"strings"
 	"time"
 
 	"github.com/chorse-dev/cdash-proxy/algorithm"
+	"github.com/chorse-dev/cdash-proxy/ctestxml/memcheck"
 	"github.com/chorse-dev/cdash-proxy/model"
+	"github.com/chorse-dev/cdash-proxy/profile"
 )
 
-func parseTesting(tst *Testing, sub []Subproject) TimedCommands {
+func parseTesting(tst *Testing, sub []Subproject, p *profile.Profile) TimedCommands {
 	return TimedCommands{
 		StartTime: time.Unix(tst.StartTime, 0),
 		EndTime:   time.Unix(tst.EndTime, 0),
-		Commands:  transformTests(tst.Tests, sub),
+		Commands:  transformTests(tst.Tests, sub, p),
 	}
 }
 
-func transformTests(tests []Test, sub []Subproject) []model.Command {
+func transformTests(tests []Test, sub []Subproject, p *profile.Profile) []model.Command {
 	return algorithm.Map(tests, func(t Test) model.Command {
 		cmd := model.Command{
 			TestName:         t.Name,
@@ -28,7 +31,7 @@ func transformTests(tests []Test, sub []Subproject) []model.Command {
 			StdOut:           t.Output.string,
 			TargetLabels:     t.Labels,
 			WorkingDirectory: t.Path,
-			Diagnostics:      parseTestOutput(t.Output.string),
+			Diagnostics:      parseTestOutput(t.Output.string, p),
 			Attributes:       map[string]string{},
 			Measurements:     map[string]float64{},
 		}
@@ -40,6 +43,18 @@ func transformTests(tests []Test, sub []Subproject) []model.Command {
 	})
 }
 
-func parseTestOutput(log string) []model.Diagnostic {
-	return []model.Diagnostic{}
+func parseTestOutput(log string, p *profile.Profile) []model.Diagnostic {
+	diags := []model.Diagnostic{}
+	for _, parser := range p.TestOutputParsers() {
+		if parser != "compiler" {
+			diags = append(diags, memcheck.Parse(parser, log)...)
+			continue
+		}
+		for _, line := range strings.Split(log, "\n") {
+			if diag := p.Rules().ParseDiagnostic(line); diag != nil {
+				diags = append(diags, *diag)
+			}
+		}
+	}
+	return diags
 }
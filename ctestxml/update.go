@@ -5,14 +5,17 @@ package ctestxml
 
 import (
 	"encoding/xml"
+	"slices"
+	"strings"
 	"time"
 
 	"github.com/chorse-dev/cdash-proxy/model"
 )
 
-// The only relevant piece of information that we parse from Update.xml is the CommitID.
-// If there is a way to set CTEST_CHANGE_ID, then submitting Update.xml is not necessary.
-// This should be the case for builds that are triggered through github actions.
+// Update.xml reports the revision that is built and the commits that entered
+// the build since the prior revision. If there is a way to set CTEST_CHANGE_ID,
+// then submitting Update.xml is not necessary for the revision. This should be
+// the case for builds that are triggered through github actions.
 // Updating requires Write Access to the source directory.
 // We may set up a CI server that updates, and then invokes CTest with the source directory mounted as Read-Only.
 
@@ -30,11 +33,124 @@ func parseUpdate(dec *xml.Decoder, elem *xml.StartElement, project string) (*mod
 		BuildName:       update.BuildName,
 		BuildGroup:      extractGroupFromBuildstamp(update.BuildStamp),
 		ChangeID:        update.Revision,
+		PriorChangeID:   known(update.PriorRevision),
 		Generator:       update.Generator,
 		Project:         project,
 		StartUpdateTime: &startTime,
 		EndUpdateTime:   &endTime,
+		Commits:         parseCommits(update.Directories),
+		Commands:        []model.Command{parseUpdateCommand(&update, startTime, endTime)},
 	}
 
 	return job, nil
 }
+
+// parseUpdateCommand reports errors of the update in UpdateReturnStatus, like
+// a failing update command or local modifications. Like ctest_update, an
+// update with errors has the result -1.
+func parseUpdateCommand(update *Update, startTime, endTime time.Time) model.Command {
+	cmd := model.Command{
+		CommandLine: update.Command,
+		Role:        "update",
+		StartTime:   &startTime,
+		Duration:    endTime.Sub(startTime).Milliseconds(),
+	}
+	if update.Type != "" {
+		cmd.Attributes = map[string]string{"Update Type": update.Type}
+	}
+
+	if status := strings.TrimSpace(update.Status); status != "" {
+		cmd.Result = -1
+		cmd.Diagnostics = []model.Diagnostic{{
+			Line:    -1,
+			Column:  -1,
+			Type:    "Error",
+			Message: status,
+		}}
+	}
+	return cmd
+}
+
+// parseCommits groups the files by their revision. CTest reports the latest
+// revision that changed a file, so a commit whose files were all changed again
+// later is not known. Commits are ordered newest first.
+func parseCommits(dirs []UpdateDirectory) []model.Commit {
+	var commits []model.Commit
+	index := map[string]int{}
+	add := func(files []UpdateFile, status string) {
+		for _, f := range files {
+			revision := known(f.Revision)
+			i, found := index[revision]
+			if !found {
+				i = len(commits)
+				index[revision] = i
+				commits = append(commits, model.Commit{
+					Revision:       revision,
+					Author:         known(f.Author),
+					Email:          known(f.Email),
+					AuthorTime:     parseCommitDate(f.CheckinDate),
+					Committer:      known(f.Committer),
+					CommitterEmail: known(f.CommitterEmail),
+					CommitTime:     parseCommitDate(f.CommitDate),
+					Log:            strings.TrimSpace(known(f.Log)),
+				})
+			}
+			commits[i].Files = append(commits[i].Files, model.ChangedFile{
+				FilePath:      updateFilePath(f),
+				Status:        status,
+				PriorRevision: known(f.PriorRevision),
+			})
+		}
+	}
+	for _, dir := range dirs {
+		add(dir.Updated, "updated")
+		add(dir.Modified, "modified")
+		add(dir.Conflicting, "conflicting")
+	}
+
+	slices.SortStableFunc(commits, func(a, b model.Commit) int {
+		switch {
+		case a.CommitTime == nil && b.CommitTime == nil:
+			return 0
+		case a.CommitTime == nil:
+			return 1
+		case b.CommitTime == nil:
+			return -1
+		}
+		return b.CommitTime.Compare(*a.CommitTime)
+	})
+	return commits
+}
+
+func updateFilePath(f UpdateFile) string {
+	switch {
+	case f.FullName != "":
+		return f.FullName
+	case f.Directory != "":
+		return f.Directory + "/" + f.File
+	}
+	return f.File
+}
+
+// known maps the placeholder "Unknown" of CTest to an empty string.
+func known(s string) string {
+	if s == "Unknown" {
+		return ""
+	}
+	return s
+}
+
+// parseCommitDate parses dates like "2025-06-19 20:10:19 +0200". For Git,
+// CTest formats the time in UTC but appends the time zone of the author, so
+// the time zone is ignored. Subversion reports dates in ISO 8601.
+func parseCommitDate(s string) *time.Time {
+	s = strings.Replace(s, "T", " ", 1)
+	if len(s) < len(time.DateTime) {
+		return nil
+	}
+	t, err := time.Parse(time.DateTime, s[:len(time.DateTime)])
+	if err != nil {
+		return nil
+	}
+	return &t
+}
@@ -0,0 +1,121 @@
+// SPDX-FileCopyrightText: 2025 Daniel Pfeifer <daniel@pfeifer-mail.de>
+// SPDX-License-Identifier: ISC
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/chorse-dev/cdash-proxy/aggregate"
+	"github.com/chorse-dev/cdash-proxy/compare"
+	"github.com/chorse-dev/cdash-proxy/model"
+	"github.com/chorse-dev/cdash-proxy/storage"
+)
+
+// compareJobs reports the changes of a job against a baseline job, either
+// from JSON files or from a SQLite database.
+func compareJobs(args []string) error {
+	fs := flag.NewFlagSet("compare", flag.ExitOnError)
+	dbPath := fs.String("sqlite", "", "read jobs by ID from the SQLite database `file`")
+	output := fs.String("o", "", "write to `file` instead of stdout")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: cdash-proxy compare [flags] job.json baseline.json")
+		fmt.Fprintln(fs.Output(), "       cdash-proxy compare -sqlite file job_id [baseline_job_id]")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	if fs.NArg() != 2 && (*dbPath == "" || fs.NArg() != 1) {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	var job, baseline *model.Job
+	var err error
+	if *dbPath != "" {
+		job, baseline, err = loadJobs(*dbPath, fs.Arg(0), fs.Arg(1))
+	} else {
+		job, err = readJobFile(fs.Arg(0))
+		if err == nil {
+			baseline, err = readJobFile(fs.Arg(1))
+		}
+	}
+	if err != nil {
+		return err
+	}
+
+	w := io.Writer(os.Stdout)
+	if *output != "" {
+		file, err := os.Create(*output)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		w = file
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(compare.Compare(job, baseline))
+}
+
+// loadJobs reads a job and its baseline from the database. Without an
+// explicit baseline, the previous job of the same build is used.
+func loadJobs(path, jobID, baselineID string) (*model.Job, *model.Job, error) {
+	db, err := storage.Open(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer db.Close()
+
+	ctx := context.Background()
+	job, err := db.Job(ctx, jobID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("job %s: %w", jobID, err)
+	}
+
+	var baseline *model.Job
+	if baselineID != "" {
+		baseline, err = db.Job(ctx, baselineID)
+	} else {
+		baseline, err = db.Previous(ctx, job)
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("baseline of %s: %w", jobID, err)
+	}
+	return job, baseline, nil
+}
+
+// readJobFile reads the jobs in a JSON file, as written by convert, and merges
+// them into one.
+func readJobFile(name string) (*model.Job, error) {
+	file, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	merged := &model.Job{}
+	dec := json.NewDecoder(file)
+	for {
+		var job model.Job
+		err := dec.Decode(&job)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+		if merged.JobID == "" {
+			merged.JobID = job.JobID
+		}
+		aggregate.Merge(merged, &job)
+	}
+	return merged, nil
+}
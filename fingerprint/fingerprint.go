@@ -0,0 +1,102 @@
+// SPDX-FileCopyrightText: 2025 Daniel Pfeifer <daniel@pfeifer-mail.de>
+// SPDX-License-Identifier: ISC
+
+// Package fingerprint identifies diagnostics across builds. A fingerprint
+// does not change when the code around a diagnostic moves, or when addresses,
+// temporary paths and similar run-specific values in the message change.
+package fingerprint
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"path"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/chorse-dev/cdash-proxy/model"
+)
+
+var (
+	reTempPath = regexp.MustCompile(`(?:/tmp|/var/tmp|/private/var/folders|(?i:[a-z]:\\Users\\[^\\]+\\AppData\\Local\\Temp))[/\\][^\s'"]*`)
+	reAddress  = regexp.MustCompile(`0x[0-9a-fA-F]+`)
+	reNumber   = regexp.MustCompile(`[0-9]+`)
+	reSpace    = regexp.MustCompile(`\s+`)
+)
+
+var quotes = strings.NewReplacer("‘", "'", "’", "'", "`", "'", "\"", "'")
+
+// Diagnostic returns the fingerprint of a diagnostic. The line and column are
+// not part of it. The context, if known, is the line of code that the
+// diagnostic refers to.
+func Diagnostic(d *model.Diagnostic, context string) string {
+	h := sha256.New()
+	for _, s := range []string{
+		d.Type,
+		normalizePath(d.FilePath),
+		d.Option,
+		normalizeMessage(d.Message),
+		normalizeSpace(context),
+	} {
+		h.Write([]byte(s))
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil)[:8])
+}
+
+// Job sets the fingerprints of the diagnostics of the job that have none.
+func Job(job *model.Job) {
+	for i := range job.Commands {
+		diags := job.Commands[i].Diagnostics
+		for j := range diags {
+			if diags[j].Fingerprint == "" {
+				diags[j].Fingerprint = Diagnostic(&diags[j], "")
+			}
+		}
+	}
+}
+
+// Dedupe removes diagnostics from compile commands that an earlier compile
+// command of the job reported already, like warnings in a header that is
+// included by many sources. Diagnostics need fingerprints.
+func Dedupe(job *model.Job) {
+	type key struct {
+		fingerprint  string
+		line, column int
+	}
+
+	seen := map[key]bool{}
+	for i := range job.Commands {
+		cmd := &job.Commands[i]
+		if cmd.Role != "compile" {
+			continue
+		}
+		cmd.Diagnostics = slices.DeleteFunc(cmd.Diagnostics, func(d model.Diagnostic) bool {
+			k := key{d.Fingerprint, d.Line, d.Column}
+			if seen[k] {
+				return true
+			}
+			seen[k] = true
+			return false
+		})
+	}
+}
+
+func normalizePath(p string) string {
+	if p == "" {
+		return p
+	}
+	return path.Clean(strings.ReplaceAll(p, "\\", "/"))
+}
+
+func normalizeMessage(msg string) string {
+	msg = quotes.Replace(msg)
+	msg = reTempPath.ReplaceAllString(msg, "<tmp>")
+	msg = reAddress.ReplaceAllString(msg, "0x")
+	msg = reNumber.ReplaceAllString(msg, "0")
+	return normalizeSpace(msg)
+}
+
+func normalizeSpace(s string) string {
+	return strings.TrimSpace(reSpace.ReplaceAllString(s, " "))
+}
@@ -0,0 +1,51 @@
+// SPDX-FileCopyrightText: 2025 Daniel Pfeifer <daniel@pfeifer-mail.de>
+// SPDX-License-Identifier: ISC
+
+package fingerprint
+
+import (
+	"testing"
+
+	"github.com/chorse-dev/cdash-proxy/model"
+)
+
+func TestDiagnostic(t *testing.T) {
+	a := &model.Diagnostic{FilePath: "src/a.c", Line: 3, Column: 1, Type: "Error",
+		Message: "heap-use-after-free on address 0x602000000010 in /tmp/ccX1y2.s"}
+	b := &model.Diagnostic{FilePath: "src/./a.c", Line: 12, Column: 5, Type: "Error",
+		Message: "heap-use-after-free  on address 0x6020000000f0 in /tmp/ccQ9z8.s"}
+	c := &model.Diagnostic{FilePath: "src/b.c", Line: 3, Column: 1, Type: "Error",
+		Message: "heap-use-after-free on address 0x602000000010 in /tmp/ccX1y2.s"}
+
+	if Diagnostic(a, "free(p);") != Diagnostic(b, "  free(p);") {
+		t.Error("expected equal fingerprints")
+	}
+	if Diagnostic(a, "free(p);") == Diagnostic(a, "free(q);") {
+		t.Error("expected context to be part of the fingerprint")
+	}
+	if Diagnostic(a, "") == Diagnostic(c, "") {
+		t.Error("expected different fingerprints")
+	}
+}
+
+func TestDedupe(t *testing.T) {
+	header := model.Diagnostic{FilePath: "include/util.h", Line: 135, Column: 1, Type: "Warning", Message: "rule of three"}
+	moved := header
+	moved.Line = 140
+	job := &model.Job{Commands: []model.Command{
+		{Role: "compile", Source: "a.cpp", Diagnostics: []model.Diagnostic{header}},
+		{Role: "compile", Source: "b.cpp", Diagnostics: []model.Diagnostic{header, moved}},
+		{Role: "link", Diagnostics: []model.Diagnostic{header}},
+	}}
+	Job(job)
+	Dedupe(job)
+
+	for i, want := range []int{1, 1, 1} {
+		if n := len(job.Commands[i].Diagnostics); n != want {
+			t.Errorf("commands[%d]: got %d diagnostics, want %d", i, n, want)
+		}
+	}
+	if job.Commands[1].Diagnostics[0].Line != 140 {
+		t.Errorf("expected the diagnostic at line 140 to be kept")
+	}
+}
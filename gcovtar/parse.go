@@ -8,20 +8,29 @@ import (
 	"bufio"
 	"compress/bzip2"
 	"encoding/json"
-	"fmt"
 	"io"
+	"log"
 	"path"
 	"path/filepath"
 	"strconv"
 	"strings"
 
+	"github.com/chorse-dev/cdash-proxy/fingerprint"
 	"github.com/chorse-dev/cdash-proxy/model"
+	"github.com/chorse-dev/cdash-proxy/profile"
 )
 
 /**
  * Parse a tarball of .gcov files.
  **/
 func Parse(r io.Reader, jobID string) (*model.Job, error) {
+	return ParseProfile(r, jobID, nil)
+}
+
+/**
+ * Parse a tarball of .gcov files with the processing settings of a project.
+ **/
+func ParseProfile(r io.Reader, jobID string, p *profile.Profile) (*model.Job, error) {
 	tr := tar.NewReader(bzip2.NewReader(r))
 	h := gcovTarHandler{}
 
@@ -35,7 +44,7 @@ func Parse(r io.Reader, jobID string) (*model.Job, error) {
 			return nil, err
 		}
 
-		fmt.Printf("-- Parsing file %s\n", hdr.Name)
+		log.Printf("-- Parsing file %s\n", hdr.Name)
 
 		if filepath.Base(hdr.Name) == "data.json" {
 			if err := h.ParseDataFile(tr); err != nil {
@@ -52,14 +61,15 @@ func Parse(r io.Reader, jobID string) (*model.Job, error) {
 
 	var cleanCoverace []model.Coverage
 	for _, c := range h.Coverage {
-		if strings.HasPrefix(c.FilePath, h.SourceDirectory) {
-			c.FilePath = c.FilePath[len(h.SourceDirectory):]
-		} else {
+		if !strings.HasPrefix(c.FilePath, h.SourceDirectory) {
 			continue
 		}
+		if p.StripSourcePaths() {
+			c.FilePath = c.FilePath[len(h.SourceDirectory):]
+		}
 		cleanCoverace = append(cleanCoverace, c)
 	}
-	h.Coverage = cleanCoverace
+	h.Coverage = p.FilterCoverage(cleanCoverace)
 
 	// Insert coverage summary (removing any old results first)
 	////$this->CoverageSummary->RemoveAll();
@@ -83,11 +93,12 @@ func Parse(r io.Reader, jobID string) (*model.Job, error) {
 		// 	// as it cannot be retrieved from github
 		// 	// d.FilePath = d.FilePath[len(h.BinaryDirectory):]
 		// } else
-		if strings.HasPrefix(d.FilePath, h.SourceDirectory) {
-			d.FilePath = d.FilePath[len(h.SourceDirectory):]
-		} else {
+		if !strings.HasPrefix(d.FilePath, h.SourceDirectory) {
 			continue
 		}
+		if p.StripSourcePaths() {
+			d.FilePath = d.FilePath[len(h.SourceDirectory):]
+		}
 		cleanDiag = append(cleanDiag, d)
 	}
 	h.Command.Diagnostics = cleanDiag
@@ -97,6 +108,8 @@ func Parse(r io.Reader, jobID string) (*model.Job, error) {
 		Commands: []model.Command{h.Command},
 		Coverage: h.Coverage,
 	}
+	fingerprint.Job(s)
+	p.SuppressDiagnostics(s)
 	return s, nil
 }
 
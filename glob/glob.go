@@ -0,0 +1,40 @@
+// SPDX-FileCopyrightText: 2025 Daniel Pfeifer <daniel@pfeifer-mail.de>
+// SPDX-License-Identifier: ISC
+
+// Package glob matches slash-separated paths against glob patterns. In
+// patterns, "*" matches within a path element, "**" across path elements and
+// "?" matches a single character.
+package glob
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Compile translates a glob into a regular expression.
+func Compile(glob string) (*regexp.Regexp, error) {
+	var b strings.Builder
+	b.WriteString("^")
+	next := 0
+	for i, r := range glob {
+		if i < next {
+			continue
+		}
+		switch {
+		case strings.HasPrefix(glob[i:], "**/"):
+			b.WriteString("(?:.*/)?")
+			next = i + 3
+		case strings.HasPrefix(glob[i:], "**"):
+			b.WriteString(".*")
+			next = i + 2
+		case r == '*':
+			b.WriteString("[^/]*")
+		case r == '?':
+			b.WriteString("[^/]")
+		default:
+			b.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+	b.WriteString("$")
+	return regexp.Compile(b.String())
+}
@@ -0,0 +1,34 @@
+// SPDX-FileCopyrightText: 2025 Daniel Pfeifer <daniel@pfeifer-mail.de>
+// SPDX-License-Identifier: ISC
+
+package glob
+
+import "testing"
+
+func TestCompile(t *testing.T) {
+	tests := []struct {
+		glob, path string
+		match      bool
+	}{
+		{"src/*.c", "src/main.c", true},
+		{"src/*.c", "src/sub/main.c", false},
+		{"src/**/*.c", "src/main.c", true},
+		{"src/**/*.c", "src/sub/main.c", true},
+		{"src/**", "src/sub/main.c", true},
+		{"?.c", "a.c", true},
+		{"?.c", "ab.c", false},
+		{"a+b.c", "a+b.c", true},
+		{"Grüße/*.c", "Grüße/main.c", true},
+		{"?/*.c", "ü/main.c", true},
+		{"Straße/**", "Strasse/main.c", false},
+	}
+	for _, test := range tests {
+		re, err := Compile(test.glob)
+		if err != nil {
+			t.Fatalf("%s: %v", test.glob, err)
+		}
+		if got := re.MatchString(test.path); got != test.match {
+			t.Errorf("%s matches %s: got %v, want %v", test.glob, test.path, got, test.match)
+		}
+	}
+}
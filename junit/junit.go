@@ -0,0 +1,182 @@
+// SPDX-FileCopyrightText: 2025 Daniel Pfeifer <daniel@pfeifer-mail.de>
+// SPDX-License-Identifier: ISC
+
+// Package junit exports the tests of a job as JUnit XML.
+package junit
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"time"
+
+	"github.com/chorse-dev/cdash-proxy/model"
+)
+
+// DefaultSuite is the name of the suite of tests without subproject and
+// labels.
+const DefaultSuite = "tests"
+
+type TestSuites struct {
+	XMLName  xml.Name    `xml:"testsuites"`
+	Name     string      `xml:"name,attr,omitempty"`
+	Tests    int         `xml:"tests,attr"`
+	Failures int         `xml:"failures,attr"`
+	Skipped  int         `xml:"skipped,attr"`
+	Time     string      `xml:"time,attr"`
+	Suites   []TestSuite `xml:"testsuite"`
+}
+
+type TestSuite struct {
+	Name      string     `xml:"name,attr"`
+	Tests     int        `xml:"tests,attr"`
+	Failures  int        `xml:"failures,attr"`
+	Skipped   int        `xml:"skipped,attr"`
+	Time      string     `xml:"time,attr"`
+	Timestamp string     `xml:"timestamp,attr,omitempty"`
+	Hostname  string     `xml:"hostname,attr,omitempty"`
+	Cases     []TestCase `xml:"testcase"`
+}
+
+type TestCase struct {
+	Name       string     `xml:"name,attr"`
+	ClassName  string     `xml:"classname,attr"`
+	Time       string     `xml:"time,attr"`
+	Status     string     `xml:"status,attr,omitempty"`
+	Properties []Property `xml:"properties>property,omitempty"`
+	Failure    *Result    `xml:"failure"`
+	Skipped    *Result    `xml:"skipped"`
+	SystemOut  string     `xml:"system-out,omitempty"`
+}
+
+type Property struct {
+	Name  string `xml:"name,attr"`
+	Value string `xml:"value,attr"`
+}
+
+type Result struct {
+	Message string `xml:"message,attr,omitempty"`
+}
+
+// Export converts the commands with role "test" into test suites, one per
+// subproject. Tests without subproject are grouped by their first label.
+// Suites are ordered by their first test.
+func Export(job *model.Job) *TestSuites {
+	suites := &TestSuites{Name: job.BuildName, Suites: []TestSuite{}}
+
+	var timestamp, hostname string
+	if job.StartTestTime != nil {
+		timestamp = job.StartTestTime.UTC().Format(time.RFC3339)
+	}
+	if job.Host != nil {
+		hostname = job.Host.Name
+	}
+
+	index := map[string]int{}
+	var durations []int64
+	for _, cmd := range job.Commands {
+		if cmd.Role != "test" {
+			continue
+		}
+
+		name := suiteName(&cmd)
+		i, found := index[name]
+		if !found {
+			i = len(suites.Suites)
+			index[name] = i
+			suites.Suites = append(suites.Suites, TestSuite{
+				Name:      name,
+				Timestamp: timestamp,
+				Hostname:  hostname,
+			})
+			durations = append(durations, 0)
+		}
+
+		suite := &suites.Suites[i]
+		tc := testCase(&cmd, name)
+		suite.Cases = append(suite.Cases, tc)
+		suite.Tests++
+		suites.Tests++
+		if tc.Failure != nil {
+			suite.Failures++
+			suites.Failures++
+		}
+		if tc.Skipped != nil {
+			suite.Skipped++
+			suites.Skipped++
+		}
+		durations[i] += cmd.Duration
+	}
+
+	var total int64
+	for i, d := range durations {
+		suites.Suites[i].Time = seconds(d)
+		total += d
+	}
+	suites.Time = seconds(total)
+	return suites
+}
+
+// Write writes the test suites of a job as indented XML.
+func Write(w io.Writer, job *model.Job) error {
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(Export(job)); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+func suiteName(cmd *model.Command) string {
+	if sub := cmd.Attributes["Subproject"]; sub != "" {
+		return sub
+	}
+	if len(cmd.TargetLabels) != 0 {
+		return cmd.TargetLabels[0]
+	}
+	return DefaultSuite
+}
+
+// testCase maps the status of a test. Tests that failed become failures with
+// the "Fail Reason" or "Exit Code" as message, tests that did not run are
+// skipped with their "Completion Status".
+func testCase(cmd *model.Command, suite string) TestCase {
+	tc := TestCase{
+		Name:      cmd.TestName,
+		ClassName: suite,
+		Time:      seconds(cmd.Duration),
+		Status:    cmd.TestStatus,
+		SystemOut: cmd.StdOut,
+	}
+
+	for _, label := range cmd.TargetLabels {
+		tc.Properties = append(tc.Properties, Property{Name: "label", Value: label})
+	}
+	for _, key := range slices.Sorted(maps.Keys(cmd.Attributes)) {
+		tc.Properties = append(tc.Properties, Property{Name: key, Value: cmd.Attributes[key]})
+	}
+
+	switch cmd.TestStatus {
+	case "passed":
+	case "failed":
+		message := cmd.Attributes["Fail Reason"]
+		if message == "" {
+			message = cmd.Attributes["Exit Code"]
+		}
+		tc.Failure = &Result{Message: message}
+	default:
+		tc.Skipped = &Result{Message: cmd.Attributes["Completion Status"]}
+	}
+	return tc
+}
+
+// seconds formats a duration in milliseconds.
+func seconds(ms int64) string {
+	return fmt.Sprintf("%d.%03d", ms/1000, ms%1000)
+}
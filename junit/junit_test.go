@@ -0,0 +1,99 @@
+// SPDX-FileCopyrightText: 2025 Daniel Pfeifer <daniel@pfeifer-mail.de>
+// SPDX-License-Identifier: ISC
+
+package junit
+
+import (
+	"bytes"
+	"encoding/xml"
+	"testing"
+	"time"
+
+	"github.com/chorse-dev/cdash-proxy/model"
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+)
+
+func TestExport(t *testing.T) {
+	start := time.Date(2025, 2, 11, 11, 37, 49, 0, time.UTC)
+	job := &model.Job{
+		BuildName:     "linux",
+		Host:          &model.Host{Name: "ci"},
+		StartTestTime: &start,
+		Commands: []model.Command{
+			{Role: "compile", Source: "a.c"},
+			{Role: "test", TestName: "core.add", TestStatus: "passed", Duration: 1500,
+				TargetLabels: []string{"core", "fast"}, StdOut: "ok",
+				Attributes: map[string]string{"Subproject": "Core"}},
+			{Role: "test", TestName: "io.read", TestStatus: "failed", Duration: 25,
+				TargetLabels: []string{"io"},
+				Attributes:   map[string]string{"Fail Reason": "Required regular expression not found."}},
+			{Role: "test", TestName: "core.sub", TestStatus: "notrun",
+				Attributes: map[string]string{"Subproject": "Core", "Completion Status": "Disabled"}},
+			{Role: "test", TestName: "misc", TestStatus: "failed", Duration: 2,
+				Attributes: map[string]string{"Exit Code": "SEGFAULT"}},
+		},
+	}
+
+	want := &TestSuites{
+		Name: "linux", Tests: 4, Failures: 2, Skipped: 1, Time: "1.527",
+		Suites: []TestSuite{
+			{
+				Name: "Core", Tests: 2, Skipped: 1, Time: "1.500",
+				Timestamp: "2025-02-11T11:37:49Z", Hostname: "ci",
+				Cases: []TestCase{
+					{Name: "core.add", ClassName: "Core", Time: "1.500", Status: "passed", SystemOut: "ok",
+						Properties: []Property{
+							{Name: "label", Value: "core"},
+							{Name: "label", Value: "fast"},
+							{Name: "Subproject", Value: "Core"},
+						}},
+					{Name: "core.sub", ClassName: "Core", Time: "0.000", Status: "notrun",
+						Properties: []Property{
+							{Name: "Completion Status", Value: "Disabled"},
+							{Name: "Subproject", Value: "Core"},
+						},
+						Skipped: &Result{Message: "Disabled"}},
+				},
+			},
+			{
+				Name: "io", Tests: 1, Failures: 1, Time: "0.025",
+				Timestamp: "2025-02-11T11:37:49Z", Hostname: "ci",
+				Cases: []TestCase{
+					{Name: "io.read", ClassName: "io", Time: "0.025", Status: "failed",
+						Properties: []Property{
+							{Name: "label", Value: "io"},
+							{Name: "Fail Reason", Value: "Required regular expression not found."},
+						},
+						Failure: &Result{Message: "Required regular expression not found."}},
+				},
+			},
+			{
+				Name: DefaultSuite, Tests: 1, Failures: 1, Time: "0.002",
+				Timestamp: "2025-02-11T11:37:49Z", Hostname: "ci",
+				Cases: []TestCase{
+					{Name: "misc", ClassName: DefaultSuite, Time: "0.002", Status: "failed",
+						Properties: []Property{{Name: "Exit Code", Value: "SEGFAULT"}},
+						Failure:    &Result{Message: "SEGFAULT"}},
+				},
+			},
+		},
+	}
+
+	got := Export(job)
+	if diff := cmp.Diff(want, got, cmpopts.IgnoreFields(TestSuites{}, "XMLName")); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+
+	var buf bytes.Buffer
+	if err := Write(&buf, job); err != nil {
+		t.Fatal(err)
+	}
+	var decoded TestSuites
+	if err := xml.Unmarshal(buf.Bytes(), &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if decoded.Tests != want.Tests || len(decoded.Suites) != len(want.Suites) {
+		t.Errorf("unexpected round trip %+v", decoded)
+	}
+}
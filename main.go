@@ -4,26 +4,116 @@
 package main
 
 import (
-	"context"
-	"encoding/json"
+	"flag"
 	"fmt"
-	"log"
-	"net/http"
+	"os"
+	"strings"
+	"time"
 
-	"github.com/chorse-dev/cdash-proxy/model"
-	"github.com/chorse-dev/cdash-proxy/web"
+	"github.com/chorse-dev/cdash-proxy/aggregate"
+	"github.com/chorse-dev/cdash-proxy/config"
+	"github.com/chorse-dev/cdash-proxy/sink"
 )
 
-func print(_ context.Context, job *model.Job) error {
-	jobJSON, err := json.MarshalIndent(job, "", "  ")
+var commands = map[string]func(args []string) error{
+	"serve":    serve,
+	"replay":   replay,
+	"convert":  convert,
+	"baseline": baseline,
+	"compare":  compareJobs,
+}
+
+func main() {
+	args := os.Args[1:]
+	command := "serve"
+	if len(args) != 0 {
+		if _, found := commands[args[0]]; found {
+			command = args[0]
+			args = args[1:]
+		}
+	}
+
+	if err := commands[command](args); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+// registerPipeline registers the flags that are shared by the subcommands
+// that produce jobs.
+func registerPipeline(fs *flag.FlagSet, cfg *config.Config) {
+	fs.Var(&listFlag{list: &cfg.Sinks}, "sink",
+		"store jobs in `kind:argument` (stdout, ndjson:<dir>, dir:<dir>, sqlite:<file>, otlp:<url>); may be repeated")
+	fs.DurationVar((*time.Duration)(&cfg.IdleTimeout), "idle-timeout", time.Duration(cfg.IdleTimeout),
+		"emit a job if no further part was received within this duration")
+}
+
+func openPipeline(cfg *config.Config) (*sink.Fanout, *aggregate.Aggregator, error) {
+	sinks := cfg.Sinks
+	if len(sinks) == 0 {
+		sinks = []string{"stdout"}
+	}
+
+	out, err := sink.OpenAll(sinks)
 	if err != nil {
+		return nil, nil, err
+	}
+
+	agg := aggregate.New(out.Store, time.Duration(cfg.IdleTimeout))
+	agg.MaxRetries = cfg.Retries
+	agg.Profiles = cfg.Profiles()
+	return out, agg, nil
+}
+
+// parseFlags parses args into cfg. Flags take precedence over environment
+// variables, which take precedence over the configuration file.
+func parseFlags(fs *flag.FlagSet, cfg *config.Config, args []string) error {
+	path := fs.String("config", os.Getenv(config.EnvPrefix+"CONFIG"),
+		"read settings from JSON `file`")
+	fs.Parse(args)
+
+	if *path != "" {
+		if err := cfg.Load(*path); err != nil {
+			return err
+		}
+	}
+	if err := cfg.ApplyEnv(os.LookupEnv); err != nil {
 		return err
 	}
 
-	fmt.Println(string(jobJSON))
+	// Parse again, so flags override the file and the environment.
+	fs.Visit(func(f *flag.Flag) {
+		if l, ok := f.Value.(*listFlag); ok {
+			l.set = false
+		}
+	})
+	fs.Parse(args)
+
+	if err := cfg.Validate(); err != nil {
+		return fmt.Errorf("invalid configuration:\n%w", err)
+	}
 	return nil
 }
 
-func main() {
-	log.Fatal(http.ListenAndServe(":8080", http.HandlerFunc(web.Serve(print))))
+// listFlag is a flag that may be repeated. The first occurrence replaces the
+// default list.
+type listFlag struct {
+	list *[]string
+	set  bool
+}
+
+func (l *listFlag) String() string {
+	if l.list == nil {
+		return ""
+	}
+	return strings.Join(*l.list, ",")
+}
+
+func (l *listFlag) Set(s string) error {
+	if !l.set {
+		*l.list = nil
+		l.set = true
+	}
+	*l.list = append(*l.list, s)
+	return nil
 }
@@ -0,0 +1,233 @@
+// SPDX-FileCopyrightText: 2025 Daniel Pfeifer <daniel@pfeifer-mail.de>
+// SPDX-License-Identifier: ISC
+
+// Package metrics implements counters and histograms in the Prometheus text
+// exposition format.
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Default is the registry that the packages of cdash-proxy register to.
+var Default = &Registry{}
+
+type metric interface {
+	write(w *bufio.Writer)
+}
+
+type Registry struct {
+	mu      sync.Mutex
+	metrics []metric
+}
+
+func (r *Registry) register(m metric) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.metrics = append(r.metrics, m)
+}
+
+func (r *Registry) Write(w io.Writer) error {
+	r.mu.Lock()
+	metrics := slices.Clone(r.metrics)
+	r.mu.Unlock()
+
+	bw := bufio.NewWriter(w)
+	for _, m := range metrics {
+		m.write(bw)
+	}
+	return bw.Flush()
+}
+
+func (r *Registry) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		r.Write(w)
+	})
+}
+
+// vec holds one value per combination of label values.
+type vec[T any] struct {
+	name   string
+	help   string
+	labels []string
+
+	mu     sync.Mutex
+	values map[string]T
+	keys   map[string][]string
+}
+
+func (v *vec[T]) get(labels []string, create func() T) T {
+	if len(labels) != len(v.labels) {
+		panic(fmt.Sprintf("metric %s: expected %d labels, got %d", v.name, len(v.labels), len(labels)))
+	}
+
+	key := strings.Join(labels, "\xff")
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if value, found := v.values[key]; found {
+		return value
+	}
+	if v.values == nil {
+		v.values = map[string]T{}
+		v.keys = map[string][]string{}
+	}
+	value := create()
+	v.values[key] = value
+	v.keys[key] = slices.Clone(labels)
+	return value
+}
+
+// each calls fn for each combination of label values in a stable order.
+func (v *vec[T]) each(fn func(labels []string, value T)) {
+	v.mu.Lock()
+	keys := make([]string, 0, len(v.values))
+	for key := range v.values {
+		keys = append(keys, key)
+	}
+	v.mu.Unlock()
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		v.mu.Lock()
+		value, labels := v.values[key], v.keys[key]
+		v.mu.Unlock()
+		fn(labels, value)
+	}
+}
+
+func (v *vec[T]) format(values []string, extra []string) string {
+	var pairs []string
+	for i, name := range v.labels {
+		pairs = append(pairs, name+`="`+escape(values[i])+`"`)
+	}
+	for i := 0; i < len(extra); i += 2 {
+		pairs = append(pairs, extra[i]+`="`+escape(extra[i+1])+`"`)
+	}
+	if len(pairs) == 0 {
+		return ""
+	}
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+// labelEscaper escapes label values as the text exposition format requires.
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func escape(value string) string {
+	return labelEscaper.Replace(value)
+}
+
+func (v *vec[T]) header(w *bufio.Writer, kind string) {
+	fmt.Fprintf(w, "# HELP %s %s\n", v.name, v.help)
+	fmt.Fprintf(w, "# TYPE %s %s\n", v.name, kind)
+}
+
+// Counter is a monotonically increasing value per combination of labels.
+type Counter struct {
+	vec[*counter]
+}
+
+type counter struct {
+	mu    sync.Mutex
+	value float64
+}
+
+func NewCounter(r *Registry, name, help string, labels ...string) *Counter {
+	c := &Counter{vec[*counter]{name: name, help: help, labels: labels}}
+	r.register(c)
+	return c
+}
+
+func (c *Counter) Add(value float64, labels ...string) {
+	v := c.get(labels, func() *counter { return &counter{} })
+	v.mu.Lock()
+	v.value += value
+	v.mu.Unlock()
+}
+
+func (c *Counter) Inc(labels ...string) {
+	c.Add(1, labels...)
+}
+
+func (c *Counter) write(w *bufio.Writer) {
+	c.header(w, "counter")
+	c.each(func(labels []string, v *counter) {
+		v.mu.Lock()
+		value := v.value
+		v.mu.Unlock()
+		fmt.Fprintf(w, "%s%s %s\n", c.name, c.format(labels, nil), formatFloat(value))
+	})
+}
+
+// Histogram counts observations in buckets per combination of labels.
+type Histogram struct {
+	vec[*histogram]
+	buckets []float64
+}
+
+type histogram struct {
+	mu     sync.Mutex
+	counts []uint64
+	count  uint64
+	sum    float64
+}
+
+// Buckets for durations in seconds and for sizes in bytes.
+var (
+	DurationBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10, 30, 60}
+	SizeBuckets     = []float64{1 << 10, 1 << 14, 1 << 17, 1 << 20, 1 << 23, 1 << 26, 1 << 28}
+)
+
+func NewHistogram(r *Registry, name, help string, buckets []float64, labels ...string) *Histogram {
+	h := &Histogram{vec[*histogram]{name: name, help: help, labels: labels}, buckets}
+	r.register(h)
+	return h
+}
+
+func (h *Histogram) Observe(value float64, labels ...string) {
+	v := h.get(labels, func() *histogram {
+		return &histogram{counts: make([]uint64, len(h.buckets))}
+	})
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	for i, bound := range h.buckets {
+		if value <= bound {
+			v.counts[i]++
+		}
+	}
+	v.count++
+	v.sum += value
+}
+
+func (h *Histogram) write(w *bufio.Writer) {
+	h.header(w, "histogram")
+	h.each(func(values []string, v *histogram) {
+		v.mu.Lock()
+		counts, count, sum := slices.Clone(v.counts), v.count, v.sum
+		v.mu.Unlock()
+
+		for i, bound := range h.buckets {
+			fmt.Fprintf(w, "%s_bucket%s %d\n", h.name, h.format(values, []string{"le", formatFloat(bound)}), counts[i])
+		}
+		fmt.Fprintf(w, "%s_bucket%s %d\n", h.name, h.format(values, []string{"le", "+Inf"}), count)
+		fmt.Fprintf(w, "%s_sum%s %s\n", h.name, h.format(values, nil), formatFloat(sum))
+		fmt.Fprintf(w, "%s_count%s %d\n", h.name, h.format(values, nil), count)
+	})
+}
+
+func formatFloat(f float64) string {
+	if math.IsInf(f, 1) {
+		return "+Inf"
+	}
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
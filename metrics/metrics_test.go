@@ -0,0 +1,58 @@
+// SPDX-FileCopyrightText: 2025 Daniel Pfeifer <daniel@pfeifer-mail.de>
+// SPDX-License-Identifier: ISC
+
+package metrics
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestWrite(t *testing.T) {
+	r := &Registry{}
+	c := NewCounter(r, "uploads_total", "Number of uploads.", "kind")
+	h := NewHistogram(r, "parse_seconds", "Time spent parsing.", []float64{0.1, 1}, "kind")
+
+	c.Inc("Build")
+	c.Add(2, "Test")
+	c.Inc("Build")
+	h.Observe(0.5, "Build")
+	h.Observe(2, "Build")
+
+	var sb strings.Builder
+	if err := r.Write(&sb); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `# HELP uploads_total Number of uploads.
+# TYPE uploads_total counter
+uploads_total{kind="Build"} 2
+uploads_total{kind="Test"} 2
+# HELP parse_seconds Time spent parsing.
+# TYPE parse_seconds histogram
+parse_seconds_bucket{kind="Build",le="0.1"} 0
+parse_seconds_bucket{kind="Build",le="1"} 1
+parse_seconds_bucket{kind="Build",le="+Inf"} 2
+parse_seconds_sum{kind="Build"} 2.5
+parse_seconds_count{kind="Build"} 2
+`
+	if sb.String() != expected {
+		t.Errorf("unexpected output:\n%s", sb.String())
+	}
+}
+
+func TestEscape(t *testing.T) {
+	r := &Registry{}
+	c := NewCounter(r, "uploads_total", "Number of uploads.", "project")
+	c.Inc("a\\b \"ü\"\n\t")
+
+	var sb strings.Builder
+	if err := r.Write(&sb); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "uploads_total{project=\"a\\\\b \\\"ü\\\"\\n\t\"} 1\n"
+	if !strings.HasSuffix(sb.String(), expected) {
+		t.Errorf("unexpected output:\n%s", sb.String())
+	}
+}
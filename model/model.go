@@ -5,12 +5,17 @@ package model
 
 import "time"
 
+// ParserVersion identifies the revision of the parsers. Increment it whenever
+// a change to ctestxml or gcovtar changes the resulting jobs.
+const ParserVersion = 4
+
 type Job struct {
 	JobID              string         `json:"job_id"`
 	Project            string         `json:"project,omitempty"`
 	BuildName          string         `json:"build_name,omitempty"`
 	BuildGroup         string         `json:"build_group,omitempty"`
 	ChangeID           string         `json:"change_id,omitempty"`
+	PriorChangeID      string         `json:"prior_change_id,omitempty"`
 	Generator          string         `json:"generator,omitempty"`
 	Host               *Host          `json:"host,omitempty"`
 	StartUpdateTime    *time.Time     `json:"start_update_time,omitempty"`
@@ -25,10 +30,13 @@ type Job struct {
 	EndCoverageTime    *time.Time     `json:"end_coverage_time,omitempty"`
 	StartMemcheckTime  *time.Time     `json:"start_memcheck_time,omitempty"`
 	EndMemcheckTime    *time.Time     `json:"end_memcheck_time,omitempty"`
+	Commits            []Commit       `json:"commits,omitempty"`
 	Commands           []Command      `json:"commands,omitempty"`
 	Coverage           []Coverage     `json:"coverage,omitempty"`
 	AttachedFiles      []AttachedFile `json:"attached_files,omitempty"`
 	Done               bool           `json:"done,omitempty"`
+	ParserVersion      int            `json:"parser_version,omitempty"`
+	Reprocessed        bool           `json:"reprocessed,omitempty"`
 }
 
 type Host struct {
@@ -58,6 +66,29 @@ type OS struct {
 	Platform string `json:"platform"`
 }
 
+// Commit is a revision that entered the job through the update step, together
+// with the files it changed. Files that were modified locally belong to a
+// commit without revision.
+type Commit struct {
+	Revision       string        `json:"revision,omitempty"`
+	Author         string        `json:"author,omitempty"`
+	Email          string        `json:"email,omitempty"`
+	AuthorTime     *time.Time    `json:"author_time,omitempty"`
+	Committer      string        `json:"committer,omitempty"`
+	CommitterEmail string        `json:"committer_email,omitempty"`
+	CommitTime     *time.Time    `json:"commit_time,omitempty"`
+	Log            string        `json:"log,omitempty"`
+	Files          []ChangedFile `json:"files"`
+}
+
+// ChangedFile is a file of a commit. The status is "updated", or "modified"
+// or "conflicting" for local modifications.
+type ChangedFile struct {
+	FilePath      string `json:"file_path"`
+	Status        string `json:"status"`
+	PriorRevision string `json:"prior_revision,omitempty"`
+}
+
 type Command struct {
 	CommandLine      string             `json:"command_line"`
 	WorkingDirectory string             `json:"working_directory,omitempty"`
@@ -88,6 +119,7 @@ type AttachedFile struct {
 	Filename string `json:"filename"`
 	Type     string `json:"type"`
 	Content  []byte `json:"content"`
+	Omitted  bool   `json:"omitted,omitempty"`
 }
 
 type Coverage struct {
@@ -103,10 +135,23 @@ type Coverage struct {
 }
 
 type Diagnostic struct {
+	FilePath     string            `json:"file_path"`
+	Line         int               `json:"line"`
+	Column       int               `json:"column"`
+	Type         string            `json:"type"`
+	Message      string            `json:"message"`
+	Option       string            `json:"option"`
+	Fingerprint  string            `json:"fingerprint,omitempty"`
+	Suppressed   bool              `json:"suppressed,omitempty"`
+	SuppressedBy string            `json:"suppressed_by,omitempty"`
+	Related      []RelatedLocation `json:"related,omitempty"`
+}
+
+// RelatedLocation is another location that a diagnostic refers to, like the
+// previous declaration of a symbol.
+type RelatedLocation struct {
 	FilePath string `json:"file_path"`
 	Line     int    `json:"line"`
 	Column   int    `json:"column"`
-	Type     string `json:"type"`
-	Message  string `json:"message"`
-	Option   string `json:"option"`
+	Message  string `json:"message,omitempty"`
 }
@@ -0,0 +1,341 @@
+// SPDX-FileCopyrightText: 2025 Daniel Pfeifer <daniel@pfeifer-mail.de>
+// SPDX-License-Identifier: ISC
+
+// Package otlp exports jobs as OpenTelemetry traces in the JSON encoding of
+// OTLP/HTTP.
+package otlp
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"maps"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/chorse-dev/cdash-proxy/model"
+)
+
+// ScopeName is the instrumentation scope of the exported spans.
+const ScopeName = "github.com/chorse-dev/cdash-proxy"
+
+// Span kinds and status codes of OTLP.
+const (
+	KindInternal = 1
+
+	StatusError = 2
+)
+
+type TracesData struct {
+	ResourceSpans []ResourceSpans `json:"resourceSpans"`
+}
+
+type ResourceSpans struct {
+	Resource   Resource     `json:"resource"`
+	ScopeSpans []ScopeSpans `json:"scopeSpans"`
+}
+
+type Resource struct {
+	Attributes []KeyValue `json:"attributes"`
+}
+
+type ScopeSpans struct {
+	Scope Scope  `json:"scope"`
+	Spans []Span `json:"spans"`
+}
+
+type Scope struct {
+	Name string `json:"name"`
+}
+
+// Span is a span of a trace. IDs are hex encoded, timestamps are nanoseconds
+// since the epoch encoded as decimal strings.
+type Span struct {
+	TraceID           string     `json:"traceId"`
+	SpanID            string     `json:"spanId"`
+	ParentSpanID      string     `json:"parentSpanId,omitempty"`
+	Name              string     `json:"name"`
+	Kind              int        `json:"kind"`
+	StartTimeUnixNano string     `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string     `json:"endTimeUnixNano"`
+	Attributes        []KeyValue `json:"attributes,omitempty"`
+	Status            *Status    `json:"status,omitempty"`
+}
+
+type Status struct {
+	Code    int    `json:"code"`
+	Message string `json:"message,omitempty"`
+}
+
+type KeyValue struct {
+	Key   string   `json:"key"`
+	Value AnyValue `json:"value"`
+}
+
+// AnyValue holds one of its fields. Integers are encoded as decimal strings.
+type AnyValue struct {
+	StringValue *string  `json:"stringValue,omitempty"`
+	IntValue    *string  `json:"intValue,omitempty"`
+	DoubleValue *float64 `json:"doubleValue,omitempty"`
+	BoolValue   *bool    `json:"boolValue,omitempty"`
+}
+
+// attributes collects key values. Empty strings are left out.
+type attributes []KeyValue
+
+func (a *attributes) str(key, value string) {
+	if value != "" {
+		*a = append(*a, KeyValue{Key: key, Value: AnyValue{StringValue: &value}})
+	}
+}
+
+func (a *attributes) integer(key string, value int64) {
+	s := strconv.FormatInt(value, 10)
+	*a = append(*a, KeyValue{Key: key, Value: AnyValue{IntValue: &s}})
+}
+
+func (a *attributes) double(key string, value float64) {
+	*a = append(*a, KeyValue{Key: key, Value: AnyValue{DoubleValue: &value}})
+}
+
+func (a *attributes) flag(key string, value bool) {
+	*a = append(*a, KeyValue{Key: key, Value: AnyValue{BoolValue: &value}})
+}
+
+// phase is a step of a job.
+type phase struct {
+	name       string
+	start, end *time.Time
+}
+
+func phases(job *model.Job) []phase {
+	return []phase{
+		{"update", job.StartUpdateTime, job.EndUpdateTime},
+		{"configure", job.StartConfigureTime, job.EndConfigureTime},
+		{"build", job.StartBuildTime, job.EndBuildTime},
+		{"test", job.StartTestTime, job.EndTestTime},
+		{"coverage", job.StartCoverageTime, job.EndCoverageTime},
+		{"memcheck", job.StartMemcheckTime, job.EndMemcheckTime},
+	}
+}
+
+// phaseOf returns the phase that a command belongs to.
+func phaseOf(cmd *model.Command) string {
+	switch cmd.Role {
+	case "update", "configure":
+		return cmd.Role
+	case "generate":
+		return "configure"
+	case "test":
+		if cmd.Attributes["DA Checker"] != "" {
+			return "memcheck"
+		}
+		return "test"
+	case "":
+		return "coverage"
+	}
+	return "build"
+}
+
+// Export converts a job into a trace: a root span for the job, a span for
+// each phase with start and end time, and a span for each command within its
+// phase. Tests have no start time; they are placed one after another from the
+// start of their phase and marked as estimated. Other commands without start
+// time are left out. IDs are derived from the job ID, so a job that is
+// exported again results in the same trace. A job without any times has no
+// spans.
+func Export(job *model.Job) *TracesData {
+	traceID := id(job.JobID, 16)
+	spans := []Span{}
+	var first, last time.Time
+	extend := func(start, end time.Time) {
+		if first.IsZero() || start.Before(first) {
+			first = start
+		}
+		if end.After(last) {
+			last = end
+		}
+	}
+
+	rootID := id(job.JobID+"/job", 8)
+	parents := map[string]string{}
+	next := map[string]time.Time{}
+	for _, p := range phases(job) {
+		if p.start == nil || p.end == nil {
+			continue
+		}
+		spanID := id(job.JobID+"/"+p.name, 8)
+		parents[p.name] = spanID
+		next[p.name] = *p.start
+		extend(*p.start, *p.end)
+		spans = append(spans, Span{
+			TraceID:           traceID,
+			SpanID:            spanID,
+			ParentSpanID:      rootID,
+			Name:              p.name,
+			Kind:              KindInternal,
+			StartTimeUnixNano: nanos(*p.start),
+			EndTimeUnixNano:   nanos(*p.end),
+		})
+	}
+
+	for i := range job.Commands {
+		cmd := &job.Commands[i]
+		p := phaseOf(cmd)
+		d := time.Duration(max(cmd.Duration, 0)) * time.Millisecond
+
+		var start time.Time
+		estimated := false
+		switch {
+		case cmd.StartTime != nil:
+			start = *cmd.StartTime
+		case cmd.Role == "test" && !next[p].IsZero():
+			start = next[p]
+			next[p] = start.Add(d)
+			estimated = true
+		default:
+			continue
+		}
+		extend(start, start.Add(d))
+
+		parent := parents[p]
+		if parent == "" {
+			parent = rootID
+		}
+		span := Span{
+			TraceID:           traceID,
+			SpanID:            id(job.JobID+"/"+strconv.Itoa(i), 8),
+			ParentSpanID:      parent,
+			Name:              name(cmd),
+			Kind:              KindInternal,
+			StartTimeUnixNano: nanos(start),
+			EndTimeUnixNano:   nanos(start.Add(d)),
+			Attributes:        commandAttributes(cmd, estimated),
+		}
+		if failed(cmd) {
+			span.Status = &Status{Code: StatusError, Message: cmd.Attributes["Fail Reason"]}
+		}
+		spans = append(spans, span)
+	}
+
+	if len(spans) == 0 {
+		return &TracesData{ResourceSpans: []ResourceSpans{}}
+	}
+
+	var attrs attributes
+	attrs.str("cdash.job_id", job.JobID)
+	attrs.str("cdash.project", job.Project)
+	attrs.str("cdash.build_name", job.BuildName)
+	attrs.str("cdash.build_group", job.BuildGroup)
+	attrs.str("cdash.change_id", job.ChangeID)
+	attrs.str("cdash.generator", job.Generator)
+	name := job.BuildName
+	if name == "" {
+		name = job.JobID
+	}
+	spans = append([]Span{{
+		TraceID:           traceID,
+		SpanID:            rootID,
+		Name:              name,
+		Kind:              KindInternal,
+		StartTimeUnixNano: nanos(first),
+		EndTimeUnixNano:   nanos(last),
+		Attributes:        attrs,
+	}}, spans...)
+
+	return &TracesData{ResourceSpans: []ResourceSpans{{
+		Resource: Resource{Attributes: resourceAttributes(job)},
+		ScopeSpans: []ScopeSpans{{
+			Scope: Scope{Name: ScopeName},
+			Spans: spans,
+		}},
+	}}}
+}
+
+// Write writes the trace of a job as OTLP JSON.
+func Write(w io.Writer, job *model.Job) error {
+	return json.NewEncoder(w).Encode(Export(job))
+}
+
+// resourceAttributes describe the host that ran the job, following the
+// semantic conventions of OpenTelemetry where there are some.
+func resourceAttributes(job *model.Job) []KeyValue {
+	attrs := attributes{}
+	attrs.str("service.name", "ctest")
+	if h := job.Host; h != nil {
+		attrs.str("cdash.site", h.Site)
+		attrs.str("host.name", h.Name)
+		attrs.str("host.arch", h.OS.Platform)
+		attrs.str("host.cpu.vendor.id", h.CPU.VendorID)
+		attrs.str("host.cpu.family", strconv.Itoa(h.CPU.FamilyID))
+		attrs.str("host.cpu.model.id", strconv.Itoa(h.CPU.ModelID))
+		attrs.str("host.cpu.model.name", h.CPU.ModelName)
+		attrs.integer("host.cpu.logical_cores", int64(h.CPU.LogicalCores))
+		attrs.integer("host.cpu.physical_cores", int64(h.CPU.PhysicalCores))
+		attrs.str("os.name", h.OS.Name)
+		attrs.str("os.version", h.OS.Release)
+		attrs.str("os.description", h.OS.Version)
+		attrs.integer("host.memory.physical", int64(h.PhysicalMemory))
+	}
+	return attrs
+}
+
+func commandAttributes(cmd *model.Command, estimated bool) []KeyValue {
+	var attrs attributes
+	attrs.str("cdash.role", cmd.Role)
+	attrs.str("cdash.target", cmd.Target)
+	attrs.str("cdash.target_type", cmd.TargetType)
+	attrs.str("cdash.source", cmd.Source)
+	attrs.str("cdash.language", cmd.Language)
+	attrs.str("cdash.config", cmd.Config)
+	attrs.str("cdash.test_name", cmd.TestName)
+	attrs.str("cdash.test_status", cmd.TestStatus)
+	attrs.str("process.command_line", cmd.CommandLine)
+	attrs.str("process.working_directory", cmd.WorkingDirectory)
+	if cmd.Role != "test" || cmd.TestStatus == "failed" {
+		attrs.integer("process.exit.code", int64(cmd.Result))
+	}
+	if n := len(cmd.Diagnostics); n != 0 {
+		attrs.integer("cdash.diagnostics", int64(n))
+	}
+	for _, m := range slices.Sorted(maps.Keys(cmd.Measurements)) {
+		attrs.double("cdash.measurement."+m, cmd.Measurements[m])
+	}
+	if estimated {
+		attrs.flag("cdash.estimated", true)
+	}
+	return attrs
+}
+
+func failed(cmd *model.Command) bool {
+	if cmd.Role == "test" {
+		return cmd.TestStatus == "failed"
+	}
+	return cmd.Result != 0
+}
+
+func name(cmd *model.Command) string {
+	switch {
+	case cmd.TestName != "":
+		return cmd.TestName
+	case cmd.Source != "":
+		return cmd.Role + " " + cmd.Source
+	case cmd.Target != "":
+		return cmd.Role + " " + cmd.Target
+	case cmd.Role != "":
+		return cmd.Role
+	}
+	return "coverage"
+}
+
+// id derives an ID of n bytes from a key.
+func id(key string, n int) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:n])
+}
+
+func nanos(t time.Time) string {
+	return strconv.FormatInt(t.UnixNano(), 10)
+}
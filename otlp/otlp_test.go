@@ -0,0 +1,93 @@
+// SPDX-FileCopyrightText: 2025 Daniel Pfeifer <daniel@pfeifer-mail.de>
+// SPDX-License-Identifier: ISC
+
+package otlp
+
+import (
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/chorse-dev/cdash-proxy/model"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestExport(t *testing.T) {
+	t0 := time.Date(2025, 5, 19, 20, 0, 0, 0, time.UTC)
+	at := func(ms int) *time.Time {
+		t := t0.Add(time.Duration(ms) * time.Millisecond)
+		return &t
+	}
+
+	job := &model.Job{
+		JobID:          "job",
+		BuildName:      "Linux",
+		Host:           &model.Host{Name: "ci", OS: model.OS{Name: "Linux"}},
+		StartBuildTime: at(0),
+		EndBuildTime:   at(500),
+		StartTestTime:  at(1000),
+		EndTestTime:    at(1100),
+		Commands: []model.Command{
+			{Role: "compile", Target: "app", Source: "a.c", Language: "C", StartTime: at(0), Duration: 300},
+			{Role: "link", Target: "app", StartTime: at(300), Duration: 200, Result: 1},
+			{Role: "compile", Source: "failed.c"},
+			{Role: "test", TestName: "one", TestStatus: "passed", Duration: 50},
+			{Role: "test", TestName: "two", TestStatus: "failed", Duration: 20,
+				Attributes: map[string]string{"Fail Reason": "Required regular expression not found."}},
+		},
+	}
+
+	type span struct {
+		Name, Parent string
+		Start, End   int
+		Status       *Status
+	}
+	var got []span
+	ids := map[string]string{}
+	spans := Export(job).ResourceSpans[0].ScopeSpans[0].Spans
+	for _, s := range spans {
+		ids[s.SpanID] = s.Name
+		if s.TraceID != spans[0].TraceID {
+			t.Errorf("span %s has trace ID %s", s.Name, s.TraceID)
+		}
+	}
+	ms := func(nanos string) int {
+		n, _ := strconv.ParseInt(nanos, 10, 64)
+		return int(time.Unix(0, n).Sub(t0).Milliseconds())
+	}
+	for _, s := range spans {
+		got = append(got, span{s.Name, ids[s.ParentSpanID], ms(s.StartTimeUnixNano), ms(s.EndTimeUnixNano), s.Status})
+	}
+
+	want := []span{
+		{Name: "Linux", Start: 0, End: 1100},
+		{Name: "build", Parent: "Linux", Start: 0, End: 500},
+		{Name: "test", Parent: "Linux", Start: 1000, End: 1100},
+		{Name: "compile a.c", Parent: "build", Start: 0, End: 300},
+		{Name: "link app", Parent: "build", Start: 300, End: 500, Status: &Status{Code: StatusError}},
+		{Name: "one", Parent: "test", Start: 1000, End: 1050},
+		{Name: "two", Parent: "test", Start: 1050, End: 1070,
+			Status: &Status{Code: StatusError, Message: "Required regular expression not found."}},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("Export() mismatch (-want +got):\n%s", diff)
+	}
+
+	attrs := map[string]AnyValue{}
+	for _, kv := range spans[6].Attributes {
+		attrs[kv.Key] = kv.Value
+	}
+	if v := attrs["cdash.test_status"].StringValue; v == nil || *v != "failed" {
+		t.Errorf("unexpected test status %v", v)
+	}
+	if v := attrs["cdash.estimated"].BoolValue; v == nil || !*v {
+		t.Errorf("test is not estimated")
+	}
+}
+
+func TestExportWithoutTimes(t *testing.T) {
+	job := &model.Job{JobID: "job", Commands: []model.Command{{Role: "compile"}}}
+	if n := len(Export(job).ResourceSpans); n != 0 {
+		t.Errorf("expected no spans, got %d resource spans", n)
+	}
+}
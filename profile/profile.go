@@ -0,0 +1,204 @@
+// SPDX-FileCopyrightText: 2025 Daniel Pfeifer <daniel@pfeifer-mail.de>
+// SPDX-License-Identifier: ISC
+
+// Package profile controls how the uploads of a project are processed.
+package profile
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+
+	"github.com/chorse-dev/cdash-proxy/ctestxml/buildparser"
+	"github.com/chorse-dev/cdash-proxy/glob"
+	"github.com/chorse-dev/cdash-proxy/model"
+	"github.com/chorse-dev/cdash-proxy/suppress"
+)
+
+// Profile holds the processing settings of a project. The zero value, as
+// well as a nil profile, processes uploads the default way. A profile must
+// be compiled before it is used.
+type Profile struct {
+	// StripSourcePath makes source paths relative to the source directory
+	// and prefixes paths in the build directory with "<build>/". It is
+	// enabled unless set to false.
+	StripSourcePath *bool `json:"strip_source_path,omitempty"`
+
+	// Memcheck lists the checkers whose logs are parsed for diagnostics.
+	// If nil, all checkers are parsed; an empty list disables all.
+	Memcheck []string `json:"memcheck,omitempty"`
+
+	// TestOutput lists the parsers that extract diagnostics from the
+	// output of tests: "compiler" for compiler diagnostics, or the name of
+	// a memcheck checker, like "AddressSanitizer".
+	TestOutput []string `json:"test_output,omitempty"`
+
+	// Diagnostics are regular expressions that are tried before the
+	// built-in ones. The named groups file, line, column, type, message
+	// and option are mapped to the fields of the diagnostic.
+	Diagnostics []string `json:"diagnostics,omitempty"`
+
+	// ErrorMatch, ErrorException, WarningMatch and WarningException are
+	// regular expressions like CTest's CTEST_CUSTOM_ERROR_MATCH and friends.
+	// Lines matching an exception are no diagnostics of that type. Named
+	// groups are mapped like in Diagnostics.
+	ErrorMatch       []string `json:"error_match,omitempty"`
+	ErrorException   []string `json:"error_exception,omitempty"`
+	WarningMatch     []string `json:"warning_match,omitempty"`
+	WarningException []string `json:"warning_exception,omitempty"`
+
+	// Coverage restricts coverage to files that match any of the Include
+	// globs, if given, and none of the Exclude globs. In globs, "*" matches
+	// within a path element and "**" across path elements.
+	Coverage CoverageFilter `json:"coverage"`
+
+	// Suppress marks the diagnostics that match any of the rules.
+	Suppress []suppress.Rule `json:"suppress,omitempty"`
+
+	// Baseline is a file with the fingerprints of accepted diagnostics,
+	// which are marked as suppressed.
+	Baseline string `json:"baseline,omitempty"`
+
+	// MaxAttachmentSize is the size in bytes above which the content of
+	// attached files is dropped; zero means no limit.
+	MaxAttachmentSize int64 `json:"max_attachment_size,omitempty"`
+
+	rules      *buildparser.Rules
+	include    []*regexp.Regexp
+	exclude    []*regexp.Regexp
+	suppressor *suppress.Suppressor
+}
+
+// Compile checks the settings and prepares the profile for use.
+func (p *Profile) Compile() error {
+	var errs []error
+	compile := func(field string, patterns []string, fn func(string) (*regexp.Regexp, error)) []*regexp.Regexp {
+		var res []*regexp.Regexp
+		for _, pattern := range patterns {
+			re, err := fn(pattern)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", field, err))
+				continue
+			}
+			res = append(res, re)
+		}
+		return res
+	}
+
+	p.rules = &buildparser.Rules{
+		Patterns:         compile("diagnostics", p.Diagnostics, regexp.Compile),
+		ErrorMatch:       compile("error_match", p.ErrorMatch, regexp.Compile),
+		ErrorException:   compile("error_exception", p.ErrorException, regexp.Compile),
+		WarningMatch:     compile("warning_match", p.WarningMatch, regexp.Compile),
+		WarningException: compile("warning_exception", p.WarningException, regexp.Compile),
+	}
+	p.include = compile("coverage.include", p.Coverage.Include, glob.Compile)
+	p.exclude = compile("coverage.exclude", p.Coverage.Exclude, glob.Compile)
+
+	var baseline map[string]bool
+	if p.Baseline != "" {
+		var err error
+		if baseline, err = suppress.LoadBaseline(p.Baseline); err != nil {
+			errs = append(errs, fmt.Errorf("baseline: %w", err))
+		}
+	}
+	var err error
+	if p.suppressor, err = suppress.New(p.Suppress, baseline); err != nil {
+		errs = append(errs, err.(interface{ Unwrap() []error }).Unwrap()...)
+	}
+
+	if p.MaxAttachmentSize < 0 {
+		errs = append(errs, errors.New("max_attachment_size: must not be negative"))
+	}
+	return errors.Join(errs...)
+}
+
+type CoverageFilter struct {
+	Include []string `json:"include,omitempty"`
+	Exclude []string `json:"exclude,omitempty"`
+}
+
+func (p *Profile) StripSourcePaths() bool {
+	return p == nil || p.StripSourcePath == nil || *p.StripSourcePath
+}
+
+func (p *Profile) MemcheckEnabled(checker string) bool {
+	return p == nil || p.Memcheck == nil || slices.Contains(p.Memcheck, checker)
+}
+
+func (p *Profile) TestOutputParsers() []string {
+	if p == nil {
+		return nil
+	}
+	return p.TestOutput
+}
+
+// Rules returns the compiled rules for diagnostics.
+func (p *Profile) Rules() *buildparser.Rules {
+	if p == nil {
+		return nil
+	}
+	return p.rules
+}
+
+// IncludeCoverage reports whether coverage of the file is kept.
+func (p *Profile) IncludeCoverage(path string) bool {
+	if p == nil {
+		return true
+	}
+	if len(p.include) != 0 && !slices.ContainsFunc(p.include, matches(path)) {
+		return false
+	}
+	return !slices.ContainsFunc(p.exclude, matches(path))
+}
+
+// FilterCoverage removes the files that are not included.
+func (p *Profile) FilterCoverage(files []model.Coverage) []model.Coverage {
+	return slices.DeleteFunc(files, func(c model.Coverage) bool {
+		return !p.IncludeCoverage(c.FilePath)
+	})
+}
+
+// SuppressDiagnostics marks known diagnostics as suppressed.
+func (p *Profile) SuppressDiagnostics(job *model.Job) {
+	if p == nil {
+		return
+	}
+	p.suppressor.Apply(job)
+}
+
+// LimitAttachments drops the content of attached files that are too large.
+func (p *Profile) LimitAttachments(job *model.Job) {
+	if p == nil || p.MaxAttachmentSize == 0 {
+		return
+	}
+
+	limit := func(files []model.AttachedFile) {
+		for i := range files {
+			if int64(len(files[i].Content)) > p.MaxAttachmentSize {
+				files[i].Content = nil
+				files[i].Omitted = true
+			}
+		}
+	}
+
+	limit(job.AttachedFiles)
+	for i := range job.Commands {
+		limit(job.Commands[i].AttachedFiles)
+	}
+}
+
+func matches(path string) func(*regexp.Regexp) bool {
+	return func(re *regexp.Regexp) bool {
+		return re.MatchString(path)
+	}
+}
+
+// Registry holds the profiles of the projects by name.
+type Registry map[string]*Profile
+
+// Lookup returns the profile of the project, or nil if it has none.
+func (r Registry) Lookup(project string) *Profile {
+	return r[project]
+}
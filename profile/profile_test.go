@@ -0,0 +1,51 @@
+// SPDX-FileCopyrightText: 2025 Daniel Pfeifer <daniel@pfeifer-mail.de>
+// SPDX-License-Identifier: ISC
+
+package profile
+
+import (
+	"testing"
+
+	"github.com/chorse-dev/cdash-proxy/model"
+)
+
+func TestIncludeCoverage(t *testing.T) {
+	p := &Profile{Coverage: CoverageFilter{
+		Include: []string{"src/**", "*.c"},
+		Exclude: []string{"**/test/**", "src/*_generated.cpp"},
+	}}
+	if err := p.Compile(); err != nil {
+		t.Fatal(err)
+	}
+
+	for path, want := range map[string]bool{
+		"main.c":                     true,
+		"lib/util.c":                 false,
+		"src/lib/util.cpp":           true,
+		"src/lib/test/util_test.cpp": false,
+		"src/api_generated.cpp":      false,
+		"src/lib/api_generated.cpp":  true,
+	} {
+		if got := p.IncludeCoverage(path); got != want {
+			t.Errorf("IncludeCoverage(%s) = %v, want %v", path, got, want)
+		}
+	}
+}
+
+func TestLimitAttachments(t *testing.T) {
+	p := &Profile{MaxAttachmentSize: 4}
+	job := &model.Job{
+		AttachedFiles: []model.AttachedFile{{Name: "small", Content: []byte("1234")}},
+		Commands: []model.Command{{
+			AttachedFiles: []model.AttachedFile{{Name: "large", Content: []byte("12345")}},
+		}},
+	}
+	p.LimitAttachments(job)
+
+	if f := job.AttachedFiles[0]; f.Omitted || string(f.Content) != "1234" {
+		t.Errorf("unexpected %+v", f)
+	}
+	if f := job.Commands[0].AttachedFiles[0]; !f.Omitted || f.Content != nil {
+		t.Errorf("unexpected %+v", f)
+	}
+}
@@ -0,0 +1,60 @@
+// SPDX-FileCopyrightText: 2025 Daniel Pfeifer <daniel@pfeifer-mail.de>
+// SPDX-License-Identifier: ISC
+
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/chorse-dev/cdash-proxy/config"
+	"github.com/chorse-dev/cdash-proxy/submission"
+	"github.com/chorse-dev/cdash-proxy/web"
+)
+
+// replay feeds archived uploads through the parsers again.
+func replay(args []string) error {
+	cfg := config.Default()
+	fs := flag.NewFlagSet("replay", flag.ExitOnError)
+	registerPipeline(fs, cfg)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: cdash-proxy replay [flags] directory...")
+		fs.PrintDefaults()
+	}
+	if err := parseFlags(fs, cfg, args); err != nil {
+		return err
+	}
+
+	if fs.NArg() == 0 {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	out, agg, err := openPipeline(cfg)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	ctx := context.Background()
+	process := web.Replay(agg.Handle, cfg.Profiles())
+
+	var errs []error
+	for _, dir := range fs.Args() {
+		subs, err := submission.List(dir)
+		if err != nil {
+			return err
+		}
+		for _, sub := range subs {
+			if err := process(ctx, sub); err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", sub.ID, err))
+			}
+		}
+	}
+
+	errs = append(errs, agg.Flush(ctx))
+	return errors.Join(errs...)
+}
@@ -0,0 +1,186 @@
+// SPDX-FileCopyrightText: 2025 Daniel Pfeifer <daniel@pfeifer-mail.de>
+// SPDX-License-Identifier: ISC
+
+package sarif
+
+import (
+	"encoding/json"
+	"io"
+	"net/url"
+	"path"
+	"slices"
+	"strings"
+
+	"github.com/chorse-dev/cdash-proxy/fingerprint"
+	"github.com/chorse-dev/cdash-proxy/model"
+)
+
+// Attribute marks commands whose diagnostics come from a SARIF file. Its
+// value is the name of the file.
+const Attribute = "SARIF"
+
+// Analysis is the outcome of one run of a SARIF log, like one invocation of
+// a compiler.
+type Analysis struct {
+	Tool        string
+	CommandLine string
+	Targets     []string
+	Diagnostics []model.Diagnostic
+}
+
+// Parse reads a SARIF log.
+func Parse(r io.Reader) (*Log, error) {
+	var log Log
+	if err := json.NewDecoder(r).Decode(&log); err != nil {
+		return nil, err
+	}
+	return &log, nil
+}
+
+// Import converts the runs of the log into diagnostics. URIs are resolved
+// against the base IDs defined by each run; with strip, paths relative to
+// SourceRoot are kept relative.
+func (log *Log) Import(strip bool) []Analysis {
+	analyses := make([]Analysis, len(log.Runs))
+	for i := range log.Runs {
+		analyses[i] = log.Runs[i].analysis(strip)
+	}
+	return analyses
+}
+
+func (run *Run) analysis(strip bool) Analysis {
+	a := Analysis{Tool: run.Tool.Driver.Name}
+	if len(run.Invocations) != 0 {
+		a.CommandLine = run.Invocations[0].CommandLine
+	}
+	for _, artifact := range run.Artifacts {
+		if slices.Contains(artifact.Roles, "analysisTarget") {
+			a.Targets = append(a.Targets, run.resolve(artifact.Location, strip, 0))
+		}
+	}
+
+	for _, result := range run.Results {
+		diag := model.Diagnostic{
+			Line:    -1,
+			Column:  -1,
+			Type:    diagnosticType(result.Level),
+			Message: result.Message.Text,
+			Option:  result.RuleID,
+		}
+		if diag.Option == "" && result.RuleIndex != nil {
+			if i := *result.RuleIndex; i >= 0 && i < len(run.Tool.Driver.Rules) {
+				diag.Option = run.Tool.Driver.Rules[i].ID
+			}
+		}
+
+		var context string
+		if len(result.Locations) != 0 {
+			loc := &result.Locations[0].PhysicalLocation
+			diag.FilePath, diag.Line, diag.Column = run.position(loc, strip)
+			context = codeLine(loc)
+		}
+		for _, related := range result.RelatedLocations {
+			r := model.RelatedLocation{}
+			r.FilePath, r.Line, r.Column = run.position(&related.PhysicalLocation, strip)
+			if related.Message != nil {
+				r.Message = related.Message.Text
+			}
+			diag.Related = append(diag.Related, r)
+		}
+
+		diag.Fingerprint = fingerprint.Diagnostic(&diag, context)
+		a.Diagnostics = append(a.Diagnostics, diag)
+	}
+	return a
+}
+
+func (run *Run) position(loc *PhysicalLocation, strip bool) (string, int, int) {
+	line, column := -1, -1
+	if loc.Region != nil {
+		if loc.Region.StartLine > 0 {
+			line = loc.Region.StartLine
+		}
+		if loc.Region.StartColumn > 0 {
+			column = loc.Region.StartColumn
+		}
+	}
+	return run.resolve(loc.ArtifactLocation, strip, 0), line, column
+}
+
+// resolve returns the path of an artifact. Base IDs may refer to other base
+// IDs; depth guards against cycles.
+func (run *Run) resolve(loc ArtifactLocation, strip bool, depth int) string {
+	if loc.URI == "" && loc.Index != nil {
+		if i := *loc.Index; i >= 0 && i < len(run.Artifacts) && depth < 8 {
+			return run.resolve(run.Artifacts[i].Location, strip, depth+1)
+		}
+	}
+
+	p := uriPath(loc.URI)
+	if loc.URIBaseID == "" || path.IsAbs(p) || isWindowsPath(p) {
+		return p
+	}
+	if strip && loc.URIBaseID == SourceRoot {
+		return p
+	}
+
+	base, found := run.OriginalURIBaseIDs[loc.URIBaseID]
+	if !found || depth >= 8 {
+		return p
+	}
+	dir := run.resolve(base, strip, depth+1)
+	if dir == "" {
+		return p
+	}
+	return path.Join(dir, p)
+}
+
+func uriPath(uri string) string {
+	u, err := url.Parse(uri)
+	if err != nil {
+		return uri
+	}
+	if u.Scheme != "file" {
+		if u.Scheme != "" {
+			return uri
+		}
+		return u.Path
+	}
+	p := u.Path
+	if len(p) > 2 && p[0] == '/' && isWindowsPath(p[1:]) {
+		p = p[1:]
+	}
+	return p
+}
+
+func isWindowsPath(p string) bool {
+	return len(p) > 1 && p[1] == ':'
+}
+
+// codeLine returns the line of code that a location refers to, if the log
+// contains a snippet.
+func codeLine(loc *PhysicalLocation) string {
+	if r := loc.Region; r != nil && r.Snippet != nil {
+		first, _, _ := strings.Cut(r.Snippet.Text, "\n")
+		return first
+	}
+	if c := loc.ContextRegion; c != nil && c.Snippet != nil && loc.Region != nil {
+		lines := strings.Split(c.Snippet.Text, "\n")
+		if i := loc.Region.StartLine - max(c.StartLine, 1); i >= 0 && i < len(lines) {
+			return lines[i]
+		}
+	}
+	return ""
+}
+
+// diagnosticType maps a SARIF level to the type of a diagnostic. Results
+// without level are warnings.
+func diagnosticType(level string) string {
+	switch level {
+	case "error":
+		return "Error"
+	case "note", "none":
+		return "Note"
+	}
+	return "Warning"
+}
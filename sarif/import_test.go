@@ -0,0 +1,71 @@
+// SPDX-FileCopyrightText: 2025 Daniel Pfeifer <daniel@pfeifer-mail.de>
+// SPDX-License-Identifier: ISC
+
+package sarif
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/chorse-dev/cdash-proxy/model"
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+)
+
+const cmakeLog = `{
+  "version": "2.1.0",
+  "runs": [{
+    "tool": {"driver": {"name": "CMake", "rules": [{"id": "CMake.Warning"}]}},
+    "originalUriBaseIds": {"SRCROOT": {"uri": "file:///src/"}},
+    "artifacts": [{"location": {"uri": "CMakeLists.txt", "uriBaseId": "SRCROOT"}}],
+    "results": [
+      {
+        "ruleIndex": 0,
+        "message": {"text": "Manually-specified variables were not used"},
+        "locations": [{"physicalLocation": {
+          "artifactLocation": {"index": 0},
+          "region": {"startLine": 12, "snippet": {"text": "set(FOO bar)"}}
+        }}]
+      },
+      {
+        "level": "note",
+        "message": {"text": "on Windows"},
+        "locations": [{"physicalLocation": {"artifactLocation": {"uri": "file:///C:/src/x.cmake"}}}]
+      }
+    ]
+  }]
+}`
+
+func TestImport(t *testing.T) {
+	log, err := Parse(strings.NewReader(cmakeLog))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tc := range []struct {
+		strip bool
+		path  string
+	}{
+		{true, "CMakeLists.txt"},
+		{false, "/src/CMakeLists.txt"},
+	} {
+		want := []Analysis{{
+			Tool: "CMake",
+			Diagnostics: []model.Diagnostic{
+				{FilePath: tc.path, Line: 12, Column: -1, Type: "Warning",
+					Message: "Manually-specified variables were not used", Option: "CMake.Warning"},
+				{FilePath: "C:/src/x.cmake", Line: -1, Column: -1, Type: "Note", Message: "on Windows"},
+			},
+		}}
+
+		got := log.Import(tc.strip)
+		if diff := cmp.Diff(want, got, cmpopts.IgnoreFields(model.Diagnostic{}, "Fingerprint")); diff != "" {
+			t.Errorf("strip=%v: mismatch (-want +got):\n%s", tc.strip, diff)
+		}
+		for _, d := range got[0].Diagnostics {
+			if d.Fingerprint == "" {
+				t.Errorf("strip=%v: expected fingerprint", tc.strip)
+			}
+		}
+	}
+}
@@ -0,0 +1,270 @@
+// SPDX-FileCopyrightText: 2025 Daniel Pfeifer <daniel@pfeifer-mail.de>
+// SPDX-License-Identifier: ISC
+
+// Package sarif exports the diagnostics of a job as a SARIF 2.1.0 log, the
+// format that code scanning tools consume.
+package sarif
+
+import (
+	"encoding/json"
+	"io"
+	"net/url"
+	"path"
+	"strings"
+
+	"github.com/chorse-dev/cdash-proxy/model"
+)
+
+const (
+	Version = "2.1.0"
+	Schema  = "https://json.schemastore.org/sarif-2.1.0.json"
+
+	// SourceRoot is the base ID of relative artifact locations. Paths are
+	// relative to the source directory if the profile strips it.
+	SourceRoot = "SRCROOT"
+
+	// FingerprintKey is the key of the fingerprint in partialFingerprints.
+	FingerprintKey = "cdashProxy/v1"
+)
+
+type Log struct {
+	Schema  string `json:"$schema"`
+	Version string `json:"version"`
+	Runs    []Run  `json:"runs"`
+}
+
+type Run struct {
+	Tool               Tool                        `json:"tool"`
+	Invocations        []Invocation                `json:"invocations,omitempty"`
+	OriginalURIBaseIDs map[string]ArtifactLocation `json:"originalUriBaseIds,omitempty"`
+	Artifacts          []Artifact                  `json:"artifacts,omitempty"`
+	Results            []Result                    `json:"results"`
+}
+
+type Tool struct {
+	Driver Driver `json:"driver"`
+}
+
+type Driver struct {
+	Name  string `json:"name"`
+	Rules []Rule `json:"rules,omitempty"`
+}
+
+type Rule struct {
+	ID string `json:"id"`
+}
+
+type Invocation struct {
+	CommandLine         string `json:"commandLine,omitempty"`
+	ExecutionSuccessful bool   `json:"executionSuccessful"`
+}
+
+type Artifact struct {
+	Location ArtifactLocation `json:"location"`
+	Roles    []string         `json:"roles,omitempty"`
+}
+
+type Result struct {
+	RuleID              string            `json:"ruleId,omitempty"`
+	RuleIndex           *int              `json:"ruleIndex,omitempty"`
+	Level               string            `json:"level"`
+	Message             Message           `json:"message"`
+	Locations           []Location        `json:"locations,omitempty"`
+	RelatedLocations    []Location        `json:"relatedLocations,omitempty"`
+	PartialFingerprints map[string]string `json:"partialFingerprints,omitempty"`
+	Suppressions        []Suppression     `json:"suppressions,omitempty"`
+	Properties          map[string]string `json:"properties,omitempty"`
+}
+
+type Message struct {
+	Text string `json:"text"`
+}
+
+type Location struct {
+	PhysicalLocation PhysicalLocation `json:"physicalLocation"`
+	Message          *Message         `json:"message,omitempty"`
+}
+
+type PhysicalLocation struct {
+	ArtifactLocation ArtifactLocation `json:"artifactLocation"`
+	Region           *Region          `json:"region,omitempty"`
+	ContextRegion    *Region          `json:"contextRegion,omitempty"`
+}
+
+type ArtifactLocation struct {
+	URI         string   `json:"uri,omitempty"`
+	URIBaseID   string   `json:"uriBaseId,omitempty"`
+	Index       *int     `json:"index,omitempty"`
+	Description *Message `json:"description,omitempty"`
+}
+
+type Region struct {
+	StartLine   int      `json:"startLine"`
+	StartColumn int      `json:"startColumn,omitempty"`
+	Snippet     *Message `json:"snippet,omitempty"`
+}
+
+type Suppression struct {
+	Kind          string `json:"kind"`
+	Justification string `json:"justification,omitempty"`
+}
+
+// Export converts the diagnostics of a job into a log with one run per tool.
+// The tool of a command is its dynamic analysis checker, "coverage" for
+// coverage commands, or else its role. Runs and rules are ordered by their
+// first appearance.
+func Export(job *model.Job) *Log {
+	log := &Log{
+		Schema:  Schema,
+		Version: Version,
+		Runs:    []Run{},
+	}
+
+	runs := map[string]*runBuilder{}
+	var order []string
+	for i := range job.Commands {
+		cmd := &job.Commands[i]
+		if len(cmd.Diagnostics) == 0 {
+			continue
+		}
+
+		name := toolName(cmd)
+		rb := runs[name]
+		if rb == nil {
+			rb = &runBuilder{
+				run:   Run{Tool: Tool{Driver: Driver{Name: name}}, Results: []Result{}},
+				rules: map[string]int{},
+			}
+			runs[name] = rb
+			order = append(order, name)
+		}
+		for _, d := range cmd.Diagnostics {
+			rb.add(cmd, &d)
+		}
+	}
+
+	for _, name := range order {
+		log.Runs = append(log.Runs, runs[name].run)
+	}
+	return log
+}
+
+// Write writes the log of a job as indented JSON.
+func Write(w io.Writer, job *model.Job) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(Export(job))
+}
+
+func toolName(cmd *model.Command) string {
+	if checker := cmd.Attributes["DA Checker"]; checker != "" {
+		return checker
+	}
+	if cmd.Role == "" {
+		return "coverage"
+	}
+	return cmd.Role
+}
+
+type runBuilder struct {
+	run   Run
+	rules map[string]int
+}
+
+func (rb *runBuilder) add(cmd *model.Command, d *model.Diagnostic) {
+	result := Result{
+		Level:   Level(d.Type),
+		Message: Message{Text: d.Message},
+	}
+
+	if d.Option != "" {
+		index, found := rb.rules[d.Option]
+		if !found {
+			index = len(rb.run.Tool.Driver.Rules)
+			rb.rules[d.Option] = index
+			rb.run.Tool.Driver.Rules = append(rb.run.Tool.Driver.Rules, Rule{ID: d.Option})
+		}
+		result.RuleID = d.Option
+		result.RuleIndex = &index
+	}
+
+	if d.FilePath != "" {
+		result.Locations = []Location{rb.location(d.FilePath, d.Line, d.Column)}
+	}
+	for _, r := range d.Related {
+		loc := rb.location(r.FilePath, r.Line, r.Column)
+		if r.Message != "" {
+			loc.Message = &Message{Text: r.Message}
+		}
+		result.RelatedLocations = append(result.RelatedLocations, loc)
+	}
+
+	if d.Fingerprint != "" {
+		result.PartialFingerprints = map[string]string{FingerprintKey: d.Fingerprint}
+	}
+
+	if d.Suppressed {
+		result.Suppressions = []Suppression{{Kind: "external", Justification: d.SuppressedBy}}
+	}
+
+	properties := map[string]string{}
+	for key, value := range map[string]string{
+		"target":   cmd.Target,
+		"source":   cmd.Source,
+		"testName": cmd.TestName,
+	} {
+		if value != "" {
+			properties[key] = value
+		}
+	}
+	if len(properties) != 0 {
+		result.Properties = properties
+	}
+
+	rb.run.Results = append(rb.run.Results, result)
+}
+
+func (rb *runBuilder) location(filePath string, line, column int) Location {
+	loc := PhysicalLocation{ArtifactLocation: rb.artifact(filePath)}
+	if line > 0 {
+		loc.Region = &Region{StartLine: line}
+		if column > 0 {
+			loc.Region.StartColumn = column
+		}
+	}
+	return Location{PhysicalLocation: loc}
+}
+
+// artifact returns the location of a file. Relative paths are resolved
+// against SourceRoot, absolute paths become file URIs.
+func (rb *runBuilder) artifact(filePath string) ArtifactLocation {
+	filePath = strings.ReplaceAll(filePath, `\`, "/")
+	if len(filePath) > 1 && filePath[1] == ':' {
+		filePath = "/" + filePath
+	}
+	if path.IsAbs(filePath) {
+		u := url.URL{Scheme: "file", Path: filePath}
+		return ArtifactLocation{URI: u.String()}
+	}
+
+	if rb.run.OriginalURIBaseIDs == nil {
+		rb.run.OriginalURIBaseIDs = map[string]ArtifactLocation{
+			SourceRoot: {Description: &Message{Text: "The source directory of the build."}},
+		}
+	}
+	u := url.URL{Path: filePath}
+	return ArtifactLocation{URI: u.EscapedPath(), URIBaseID: SourceRoot}
+}
+
+// Level maps the type of a diagnostic to a SARIF level.
+func Level(typ string) string {
+	switch strings.ToLower(typ) {
+	case "error":
+		return "error"
+	case "warning":
+		return "warning"
+	case "note":
+		return "note"
+	}
+	return "none"
+}
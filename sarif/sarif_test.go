@@ -0,0 +1,122 @@
+// SPDX-FileCopyrightText: 2025 Daniel Pfeifer <daniel@pfeifer-mail.de>
+// SPDX-License-Identifier: ISC
+
+package sarif
+
+import (
+	"testing"
+
+	"github.com/chorse-dev/cdash-proxy/model"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestExport(t *testing.T) {
+	job := &model.Job{
+		Commands: []model.Command{
+			{Role: "configure"},
+			{Role: "compile", Target: "app", Source: "src/main.c", Diagnostics: []model.Diagnostic{
+				{FilePath: "src/main.c", Line: 3, Column: 5, Type: "Warning", Message: "unused variable 'x'",
+					Option: "-Wunused-variable", Fingerprint: "aaaa"},
+				{FilePath: "src/my file.c", Line: 8, Column: -1, Type: "Error", Message: "expected ';'"},
+				{FilePath: "src/main.c", Line: 9, Type: "Warning", Message: "unused variable 'y'",
+					Option: "-Wunused-variable", Suppressed: true, SuppressedBy: "baseline"},
+			}},
+			{Role: "test", TestName: "leak", Attributes: map[string]string{"DA Checker": "Valgrind"},
+				Diagnostics: []model.Diagnostic{
+					{Type: "Warning", Message: "Memory Leak", Option: "Memory Leak"},
+				}},
+			{Diagnostics: []model.Diagnostic{
+				{FilePath: "/usr/include/stdio.h", Line: 0, Column: -1, Type: "Warning",
+					Message: "branch 0 taken 0%", Option: "Branch Coverage"},
+			}},
+		},
+	}
+
+	zero := 0
+	srcroot := map[string]ArtifactLocation{
+		SourceRoot: {Description: &Message{Text: "The source directory of the build."}},
+	}
+	want := &Log{
+		Schema:  Schema,
+		Version: Version,
+		Runs: []Run{
+			{
+				Tool:               Tool{Driver: Driver{Name: "compile", Rules: []Rule{{ID: "-Wunused-variable"}}}},
+				OriginalURIBaseIDs: srcroot,
+				Results: []Result{
+					{
+						RuleID:    "-Wunused-variable",
+						RuleIndex: &zero,
+						Level:     "warning",
+						Message:   Message{Text: "unused variable 'x'"},
+						Locations: []Location{{PhysicalLocation: PhysicalLocation{
+							ArtifactLocation: ArtifactLocation{URI: "src/main.c", URIBaseID: SourceRoot},
+							Region:           &Region{StartLine: 3, StartColumn: 5},
+						}}},
+						PartialFingerprints: map[string]string{FingerprintKey: "aaaa"},
+						Properties:          map[string]string{"target": "app", "source": "src/main.c"},
+					},
+					{
+						Level:   "error",
+						Message: Message{Text: "expected ';'"},
+						Locations: []Location{{PhysicalLocation: PhysicalLocation{
+							ArtifactLocation: ArtifactLocation{URI: "src/my%20file.c", URIBaseID: SourceRoot},
+							Region:           &Region{StartLine: 8},
+						}}},
+						Properties: map[string]string{"target": "app", "source": "src/main.c"},
+					},
+					{
+						RuleID:    "-Wunused-variable",
+						RuleIndex: &zero,
+						Level:     "warning",
+						Message:   Message{Text: "unused variable 'y'"},
+						Locations: []Location{{PhysicalLocation: PhysicalLocation{
+							ArtifactLocation: ArtifactLocation{URI: "src/main.c", URIBaseID: SourceRoot},
+							Region:           &Region{StartLine: 9},
+						}}},
+						Suppressions: []Suppression{{Kind: "external", Justification: "baseline"}},
+						Properties:   map[string]string{"target": "app", "source": "src/main.c"},
+					},
+				},
+			},
+			{
+				Tool: Tool{Driver: Driver{Name: "Valgrind", Rules: []Rule{{ID: "Memory Leak"}}}},
+				Results: []Result{
+					{
+						RuleID:     "Memory Leak",
+						RuleIndex:  &zero,
+						Level:      "warning",
+						Message:    Message{Text: "Memory Leak"},
+						Properties: map[string]string{"testName": "leak"},
+					},
+				},
+			},
+			{
+				Tool: Tool{Driver: Driver{Name: "coverage", Rules: []Rule{{ID: "Branch Coverage"}}}},
+				Results: []Result{
+					{
+						RuleID:    "Branch Coverage",
+						RuleIndex: &zero,
+						Level:     "warning",
+						Message:   Message{Text: "branch 0 taken 0%"},
+						Locations: []Location{{PhysicalLocation: PhysicalLocation{
+							ArtifactLocation: ArtifactLocation{URI: "file:///usr/include/stdio.h"},
+						}}},
+					},
+				},
+			},
+		},
+	}
+
+	if diff := cmp.Diff(want, Export(job)); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestArtifactWindows(t *testing.T) {
+	var rb runBuilder
+	got := rb.artifact(`C:\src\main.c`)
+	if want := "file:///C:/src/main.c"; got.URI != want {
+		t.Errorf("expected %q, got %q", want, got.URI)
+	}
+}
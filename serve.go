@@ -0,0 +1,158 @@
+// SPDX-FileCopyrightText: 2025 Daniel Pfeifer <daniel@pfeifer-mail.de>
+// SPDX-License-Identifier: ISC
+
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+	"time"
+
+	"github.com/chorse-dev/cdash-proxy/auth"
+	"github.com/chorse-dev/cdash-proxy/blob"
+	"github.com/chorse-dev/cdash-proxy/config"
+	"github.com/chorse-dev/cdash-proxy/metrics"
+	"github.com/chorse-dev/cdash-proxy/sink"
+	"github.com/chorse-dev/cdash-proxy/spool"
+	"github.com/chorse-dev/cdash-proxy/web"
+)
+
+func serve(args []string) error {
+	cfg := config.Default()
+	fs := flag.NewFlagSet("serve", flag.ExitOnError)
+	registerPipeline(fs, cfg)
+	fs.Var(&listFlag{list: &cfg.Listen}, "listen", "listen on `address`; may be repeated")
+	fs.StringVar(&cfg.TLSCert, "tls-cert", "", "serve HTTPS with the certificate in `file`")
+	fs.StringVar(&cfg.TLSKey, "tls-key", "", "private key in `file` for -tls-cert")
+	fs.DurationVar((*time.Duration)(&cfg.ReadTimeout), "read-timeout", time.Duration(cfg.ReadTimeout),
+		"maximum duration for reading a request")
+	fs.DurationVar((*time.Duration)(&cfg.WriteTimeout), "write-timeout", time.Duration(cfg.WriteTimeout),
+		"maximum duration for processing a request and writing the response")
+	fs.Int64Var(&cfg.MaxBodySize, "max-body-size", 0, "reject uploads larger than `bytes`; 0 means no limit")
+	fs.StringVar(&cfg.Spool, "spool", "",
+		"store uploads in `directory` and process them in the background")
+	fs.IntVar(&cfg.Workers, "workers", cfg.Workers, "number of background workers")
+	fs.IntVar(&cfg.Retries, "retries", cfg.Retries, "number of retries before a job that the sinks rejected is given up")
+	fs.StringVar(&cfg.Archive, "archive", "", "keep every raw upload in `directory` for replay")
+	fs.StringVar(&cfg.Uploads, "uploads", "", "keep files of the two-step upload protocol in `directory` to skip repeated uploads")
+	fs.StringVar(&cfg.Tokens, "tokens", "", "accept submissions only with bearer tokens from JSON `file`")
+	fs.BoolVar(&cfg.RejectUnknownProjects, "reject-unknown-projects", false, "reject submissions for projects without tokens")
+	fs.StringVar(&cfg.Upstream, "upstream", "", "forward every upload to the CDash submit.php at `url`")
+	fs.BoolVar(&cfg.UpstreamPrimary, "upstream-primary", false, "answer with the response of the upstream server")
+	fs.StringVar(&cfg.UpstreamFailed, "upstream-failed", "", "keep uploads that could not be forwarded in `directory`")
+	fs.DurationVar((*time.Duration)(&cfg.ShutdownTimeout), "shutdown-timeout", time.Duration(cfg.ShutdownTimeout),
+		"time to finish pending requests and background work on shutdown")
+	if err := parseFlags(fs, cfg, args); err != nil {
+		return err
+	}
+
+	out, agg, err := openPipeline(cfg)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	srv := &web.Server{
+		Handler:   agg.Handle,
+		Archive:   cfg.Archive,
+		Profiles:  cfg.Profiles(),
+		BodyLimit: cfg.BodyLimit,
+	}
+	if cfg.Tokens != "" {
+		if srv.Auth, err = auth.Load(cfg.Tokens); err != nil {
+			return err
+		}
+		srv.Auth.RejectUnknown = cfg.RejectUnknownProjects
+	} else if cfg.RejectUnknownProjects {
+		srv.Auth = &auth.Tokens{RejectUnknown: true}
+	}
+	if cfg.Uploads != "" {
+		if srv.Blobs, err = blob.NewStore(cfg.Uploads); err != nil {
+			return err
+		}
+	}
+	if cfg.Spool != "" {
+		srv.Spool = spool.New(cfg.Spool, web.Process(agg.Handle, srv.Profiles))
+		srv.Spool.Workers = cfg.Workers
+		srv.Spool.Key = web.JobID
+		dead, err := sink.NewDir(filepath.Join(cfg.Spool, "dead-jobs"))
+		if err != nil {
+			return err
+		}
+		agg.Dead = dead.Store
+		agg.Hold = spool.Hold
+		if err := srv.Spool.Start(); err != nil {
+			return err
+		}
+	}
+
+	if cfg.Upstream != "" {
+		srv.Forwarder = &web.Forwarder{
+			Upstream:  cfg.Upstream,
+			Primary:   cfg.UpstreamPrimary,
+			FailedDir: cfg.UpstreamFailed,
+		}
+	}
+
+	var health web.Health
+	mux := http.NewServeMux()
+	mux.Handle("/", srv)
+	mux.Handle("GET /metrics", metrics.Default.Handler())
+	mux.HandleFunc("GET /healthz", health.Live)
+	mux.HandleFunc("GET /readyz", health.Ready)
+	if db := out.DB(); db != nil {
+		mux.Handle("/api/", web.API(db))
+		health.Checks = append(health.Checks, db.Ping)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	var servers []*http.Server
+	errc := make(chan error, len(cfg.Listen))
+	for _, addr := range cfg.Listen {
+		hs := &http.Server{
+			Addr:         addr,
+			Handler:      mux,
+			ReadTimeout:  time.Duration(cfg.ReadTimeout),
+			WriteTimeout: time.Duration(cfg.WriteTimeout),
+		}
+		servers = append(servers, hs)
+		go func() {
+			if cfg.TLSCert != "" {
+				errc <- hs.ListenAndServeTLS(cfg.TLSCert, cfg.TLSKey)
+			} else {
+				errc <- hs.ListenAndServe()
+			}
+		}()
+	}
+	health.SetReady(true)
+
+	var errs []error
+	select {
+	case err := <-errc:
+		errs = append(errs, err)
+	case <-ctx.Done():
+	}
+
+	log.Print("shutting down")
+	health.SetReady(false)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(cfg.ShutdownTimeout))
+	defer cancel()
+
+	for _, hs := range servers {
+		errs = append(errs, hs.Shutdown(ctx))
+	}
+	if srv.Spool != nil {
+		errs = append(errs, srv.Spool.Close(ctx))
+	}
+	errs = append(errs, agg.Flush(ctx))
+	return errors.Join(errs...)
+}
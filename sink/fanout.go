@@ -0,0 +1,115 @@
+// SPDX-FileCopyrightText: 2025 Daniel Pfeifer <daniel@pfeifer-mail.de>
+// SPDX-License-Identifier: ISC
+
+package sink
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/chorse-dev/cdash-proxy/metrics"
+	"github.com/chorse-dev/cdash-proxy/model"
+	"github.com/chorse-dev/cdash-proxy/storage"
+)
+
+// Fanout stores each job in all of its sinks. A failing sink does not keep the
+// job from being stored in the others.
+type Fanout struct {
+	specs []string
+	sinks []Sink
+}
+
+func OpenAll(specs []string) (*Fanout, error) {
+	f := &Fanout{}
+	for _, spec := range specs {
+		s, err := Open(spec)
+		if err != nil {
+			f.Close()
+			return nil, err
+		}
+		f.specs = append(f.specs, spec)
+		f.sinks = append(f.sinks, s)
+	}
+	return f, nil
+}
+
+var (
+	storeSeconds = metrics.NewHistogram(metrics.Default, "cdash_proxy_sink_seconds",
+		"Time spent storing jobs.", metrics.DurationBuckets, "sink")
+	storeErrors = metrics.NewCounter(metrics.Default, "cdash_proxy_sink_errors_total",
+		"Number of jobs that could not be stored.", "sink")
+)
+
+// Store stores the job in all sinks. If some of them fail, the error is a
+// *PartialError.
+func (f *Fanout) Store(ctx context.Context, job *model.Job) error {
+	all := make([]int, len(f.sinks))
+	for i := range all {
+		all[i] = i
+	}
+	return f.store(ctx, job, all)
+}
+
+func (f *Fanout) store(ctx context.Context, job *model.Job, sinks []int) error {
+	var failed []int
+	var errs []error
+	for _, i := range sinks {
+		kind, _, _ := strings.Cut(f.specs[i], ":")
+		start := time.Now()
+		err := f.sinks[i].Store(ctx, job)
+		storeSeconds.Observe(time.Since(start).Seconds(), kind)
+		if err != nil {
+			storeErrors.Inc(kind)
+			failed = append(failed, i)
+			errs = append(errs, fmt.Errorf("sink %s: %w", f.specs[i], err))
+		}
+	}
+	if len(failed) == 0 {
+		return nil
+	}
+	return &PartialError{fanout: f, failed: failed, err: errors.Join(errs...)}
+}
+
+// PartialError reports the sinks of a Fanout that failed to store a job.
+// Retry stores the job in these sinks only, so that the others do not store
+// it twice.
+type PartialError struct {
+	fanout *Fanout
+	failed []int
+	err    error
+}
+
+func (e *PartialError) Error() string {
+	return e.err.Error()
+}
+
+func (e *PartialError) Unwrap() error {
+	return e.err
+}
+
+func (e *PartialError) Retry(ctx context.Context, job *model.Job) error {
+	return e.fanout.store(ctx, job, e.failed)
+}
+
+func (f *Fanout) Close() error {
+	var errs []error
+	for i, s := range f.sinks {
+		if err := s.Close(); err != nil {
+			errs = append(errs, fmt.Errorf("sink %s: %w", f.specs[i], err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// DB returns the first sqlite sink, or nil if there is none.
+func (f *Fanout) DB() *storage.DB {
+	for _, s := range f.sinks {
+		if db, ok := s.(*storage.DB); ok {
+			return db
+		}
+	}
+	return nil
+}
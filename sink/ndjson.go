@@ -0,0 +1,84 @@
+// SPDX-FileCopyrightText: 2025 Daniel Pfeifer <daniel@pfeifer-mail.de>
+// SPDX-License-Identifier: ISC
+
+package sink
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/chorse-dev/cdash-proxy/model"
+)
+
+// NDJSON appends one JSON document per line to a file that is rotated daily.
+type NDJSON struct {
+	dir string
+
+	mu   sync.Mutex
+	day  string
+	file *os.File
+}
+
+func NewNDJSON(dir string) (*NDJSON, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	return &NDJSON{dir: dir}, nil
+}
+
+func (s *NDJSON) Store(_ context.Context, job *model.Job) error {
+	line, err := json.Marshal(job)
+	if err != nil {
+		return err
+	}
+	line = append(line, '\n')
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.rotate(time.Now().UTC().Format(time.DateOnly)); err != nil {
+		return err
+	}
+
+	_, err = s.file.Write(line)
+	return err
+}
+
+func (s *NDJSON) rotate(day string) error {
+	if s.file != nil && s.day == day {
+		return nil
+	}
+
+	if s.file != nil {
+		if err := s.file.Close(); err != nil {
+			return err
+		}
+		s.file = nil
+	}
+
+	name := filepath.Join(s.dir, day+".ndjson")
+	file, err := os.OpenFile(name, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+
+	s.day = day
+	s.file = file
+	return nil
+}
+
+func (s *NDJSON) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.file == nil {
+		return nil
+	}
+	err := s.file.Close()
+	s.file = nil
+	return err
+}
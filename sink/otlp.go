@@ -0,0 +1,93 @@
+// SPDX-FileCopyrightText: 2025 Daniel Pfeifer <daniel@pfeifer-mail.de>
+// SPDX-License-Identifier: ISC
+
+package sink
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/chorse-dev/cdash-proxy/model"
+	"github.com/chorse-dev/cdash-proxy/otlp"
+)
+
+// OTLPTimeout is the default time limit of a request to an OTLP endpoint.
+const OTLPTimeout = 10 * time.Second
+
+// OTLP sends each job as a trace to an OTLP/HTTP endpoint, like
+// "http://localhost:4318/v1/traces" of an OpenTelemetry collector. An
+// endpoint without path gets the default path "/v1/traces". The query
+// parameter "timeout" overrides OTLPTimeout and is not sent.
+type OTLP struct {
+	endpoint string
+	Client   *http.Client
+}
+
+func NewOTLP(endpoint string) (*OTLP, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme != "http" && u.Scheme != "https" || u.Host == "" {
+		return nil, fmt.Errorf("invalid OTLP endpoint %q", endpoint)
+	}
+	if u.Path == "" || u.Path == "/" {
+		u.Path = "/v1/traces"
+	}
+
+	timeout := OTLPTimeout
+	query := u.Query()
+	if s := query.Get("timeout"); s != "" {
+		if timeout, err = time.ParseDuration(s); err != nil {
+			return nil, fmt.Errorf("invalid OTLP timeout %q", s)
+		}
+		query.Del("timeout")
+		u.RawQuery = query.Encode()
+	}
+
+	return &OTLP{
+		endpoint: u.String(),
+		Client:   &http.Client{Timeout: timeout},
+	}, nil
+}
+
+func (s *OTLP) Store(ctx context.Context, job *model.Job) error {
+	traces := otlp.Export(job)
+	if len(traces.ResourceSpans) == 0 {
+		return nil
+	}
+
+	data, err := json.Marshal(traces)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.endpoint, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := s.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("%s: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+	return nil
+}
+
+func (s *OTLP) Close() error {
+	return nil
+}
@@ -0,0 +1,43 @@
+// SPDX-FileCopyrightText: 2025 Daniel Pfeifer <daniel@pfeifer-mail.de>
+// SPDX-License-Identifier: ISC
+
+package sink
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/chorse-dev/cdash-proxy/model"
+	"github.com/chorse-dev/cdash-proxy/storage"
+)
+
+// Sink persists jobs.
+type Sink interface {
+	Store(ctx context.Context, job *model.Job) error
+	Close() error
+}
+
+// Open creates a sink from a specification of the form "kind:argument".
+//
+//	stdout              print indented JSON to stdout
+//	ndjson:<directory>  append to one file per day
+//	dir:<directory>     write one file per job
+//	sqlite:<file>       insert into an embedded database
+//	otlp:<url>          send a trace to an OTLP/HTTP endpoint
+func Open(spec string) (Sink, error) {
+	kind, arg, _ := strings.Cut(spec, ":")
+	switch kind {
+	case "stdout":
+		return Stdout{}, nil
+	case "ndjson":
+		return NewNDJSON(arg)
+	case "dir":
+		return NewDir(arg)
+	case "sqlite":
+		return storage.Open(arg)
+	case "otlp":
+		return NewOTLP(arg)
+	}
+	return nil, fmt.Errorf("unknown sink %q", spec)
+}
@@ -0,0 +1,231 @@
+// SPDX-FileCopyrightText: 2025 Daniel Pfeifer <daniel@pfeifer-mail.de>
+// SPDX-License-Identifier: ISC
+
+package sink
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/chorse-dev/cdash-proxy/aggregate"
+	"github.com/chorse-dev/cdash-proxy/ctestxml"
+	"github.com/chorse-dev/cdash-proxy/model"
+	"github.com/chorse-dev/cdash-proxy/otlp"
+)
+
+func TestFanout(t *testing.T) {
+	dir := t.TempDir()
+	specs := []string{
+		"ndjson:" + filepath.Join(dir, "ndjson"),
+		"dir:" + filepath.Join(dir, "jobs"),
+		"sqlite:" + filepath.Join(dir, "jobs.db"),
+	}
+
+	f, err := OpenAll(specs)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, name := range []string{"Configure.xml", "Build.xml", "Test.xml"} {
+		file, err := os.Open("../ctestxml/testdata/" + name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		job, err := ctestxml.Parse(file, "Example")
+		file.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := f.Store(context.Background(), job); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	day := time.Now().UTC().Format(time.DateOnly)
+	file, err := os.Open(filepath.Join(dir, "ndjson", day+".ndjson"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	lines := 0
+	for scanner := bufio.NewScanner(file); scanner.Scan(); {
+		lines++
+	}
+	if lines != 3 {
+		t.Errorf("expected 3 lines, got %d", lines)
+	}
+
+	jobs, _ := filepath.Glob(filepath.Join(dir, "jobs", "*.json"))
+	if len(jobs) == 0 {
+		t.Error("expected job files")
+	}
+}
+
+func TestFanoutError(t *testing.T) {
+	dir := t.TempDir()
+	f, err := OpenAll([]string{"dir:" + dir})
+	if err != nil {
+		t.Fatal(err)
+	}
+	os.RemoveAll(dir)
+
+	err = f.Store(context.Background(), &model.Job{JobID: "1"})
+	if err == nil || !strings.HasPrefix(err.Error(), "sink dir:") {
+		t.Errorf("expected error of dir sink, got %v", err)
+	}
+}
+
+// flaky counts the jobs it stores and fails the first fail times.
+type flaky struct {
+	fail   int
+	stored chan string
+}
+
+func (s *flaky) Store(ctx context.Context, job *model.Job) error {
+	if s.fail > 0 {
+		s.fail--
+		return errors.New("unavailable")
+	}
+	s.stored <- job.JobID
+	return nil
+}
+
+func (s *flaky) Close() error {
+	return nil
+}
+
+func TestFanoutRetry(t *testing.T) {
+	good := &flaky{stored: make(chan string, 2)}
+	bad := &flaky{fail: 1, stored: make(chan string, 2)}
+	f := &Fanout{specs: []string{"good:", "bad:"}, sinks: []Sink{good, bad}}
+
+	a := aggregate.New(f.Store, time.Hour)
+	a.Backoff = time.Millisecond
+	if err := a.Handle(context.Background(), &model.Job{JobID: "1", Done: true}); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case <-bad.stored:
+	case <-time.After(time.Second):
+		t.Fatal("job was not retried")
+	}
+	a.Flush(context.Background())
+	if n := len(good.stored); n != 1 {
+		t.Errorf("expected the job to be stored once, got %d times", n)
+	}
+}
+
+func TestOpenUnknown(t *testing.T) {
+	if _, err := Open("kafka:localhost"); err == nil {
+		t.Error("expected error for unknown sink")
+	}
+}
+
+func TestOTLP(t *testing.T) {
+	var received []otlp.TracesData
+	collector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/traces" || r.Header.Get("Content-Type") != "application/json" {
+			http.Error(w, "unexpected request", http.StatusBadRequest)
+			return
+		}
+		var traces otlp.TracesData
+		if err := json.NewDecoder(r.Body).Decode(&traces); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		received = append(received, traces)
+		w.Write([]byte("{}"))
+	}))
+	defer collector.Close()
+
+	s, err := Open("otlp:" + collector.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	file, err := os.Open("../ctestxml/testdata/Test.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	job, err := ctestxml.Parse(file, "Example")
+	file.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := s.Store(context.Background(), job); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Store(context.Background(), &model.Job{JobID: "empty"}); err != nil {
+		t.Fatal(err)
+	}
+	if len(received) != 1 {
+		t.Fatalf("expected 1 request, got %d", len(received))
+	}
+	spans := received[0].ResourceSpans[0].ScopeSpans[0].Spans
+	if want := 2 + len(job.Commands); len(spans) != want {
+		t.Errorf("expected %d spans, got %d", want, len(spans))
+	}
+}
+
+func TestOTLPError(t *testing.T) {
+	collector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "overloaded", http.StatusServiceUnavailable)
+	}))
+	defer collector.Close()
+
+	s, err := NewOTLP(collector.URL + "/v1/traces")
+	if err != nil {
+		t.Fatal(err)
+	}
+	job := &model.Job{JobID: "1", StartBuildTime: new(time.Time), EndBuildTime: new(time.Time)}
+	err = s.Store(context.Background(), job)
+	if err == nil || !strings.Contains(err.Error(), "overloaded") {
+		t.Errorf("expected error of collector, got %v", err)
+	}
+
+	if _, err := Open("otlp:localhost:4318"); err == nil {
+		t.Error("expected error for endpoint without scheme")
+	}
+}
+
+func TestOTLPTimeout(t *testing.T) {
+	collector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.RawQuery != "" {
+			http.Error(w, "unexpected query", http.StatusBadRequest)
+			return
+		}
+		time.Sleep(200 * time.Millisecond)
+	}))
+	defer collector.Close()
+
+	s, err := Open("otlp:" + collector.URL + "?timeout=20ms")
+	if err != nil {
+		t.Fatal(err)
+	}
+	job := &model.Job{JobID: "1", StartBuildTime: new(time.Time), EndBuildTime: new(time.Time)}
+	err = s.Store(context.Background(), job)
+	if err == nil || !strings.Contains(err.Error(), "Timeout") {
+		t.Errorf("expected timeout, got %v", err)
+	}
+
+	if _, err := Open("otlp:" + collector.URL + "?timeout=soon"); err == nil {
+		t.Error("expected error for invalid timeout")
+	}
+}
@@ -0,0 +1,28 @@
+// SPDX-FileCopyrightText: 2025 Daniel Pfeifer <daniel@pfeifer-mail.de>
+// SPDX-License-Identifier: ISC
+
+package sink
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/chorse-dev/cdash-proxy/model"
+)
+
+type Stdout struct{}
+
+func (Stdout) Store(_ context.Context, job *model.Job) error {
+	jobJSON, err := json.MarshalIndent(job, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	fmt.Println(string(jobJSON))
+	return nil
+}
+
+func (Stdout) Close() error {
+	return nil
+}
@@ -0,0 +1,265 @@
+// SPDX-FileCopyrightText: 2025 Daniel Pfeifer <daniel@pfeifer-mail.de>
+// SPDX-License-Identifier: ISC
+
+package spool
+
+import (
+	"context"
+	"io"
+	"log"
+	"net/url"
+	"path/filepath"
+	"sync"
+
+	"github.com/chorse-dev/cdash-proxy/submission"
+)
+
+type ProcessFunc func(ctx context.Context, sub *submission.Submission) error
+
+// Spool writes submissions to disk and processes them in the background with
+// a bounded number of workers. Submissions with the same key are processed
+// one at a time in the order they were put. Submissions that fail are moved
+// to the "dead" subdirectory; retrying is up to the process function.
+// Submissions are removed once processed, unless the process function holds
+// them.
+type Spool struct {
+	Workers int
+
+	// Key, if set, returns the key of a submission, like its job ID. An
+	// empty key imposes no order.
+	Key func(sub *submission.Submission) string
+
+	dir     string
+	process ProcessFunc
+	queue   chan *submission.Submission
+	ready   chan keyed
+
+	mu      sync.Mutex
+	waiting map[string][]*submission.Submission
+
+	wg      sync.WaitGroup
+	pending sync.WaitGroup
+	cancel  context.CancelFunc
+}
+
+func New(dir string, process ProcessFunc) *Spool {
+	return &Spool{
+		Workers: 4,
+		dir:     dir,
+		process: process,
+		queue:   make(chan *submission.Submission, 1024),
+		ready:   make(chan keyed),
+		waiting: map[string][]*submission.Submission{},
+	}
+}
+
+// DeadDir is where submissions end up that could not be processed.
+func (s *Spool) DeadDir() string {
+	return filepath.Join(s.dir, "dead")
+}
+
+// Start starts the workers and queues the submissions that were spooled but
+// not processed before the last shutdown.
+func (s *Spool) Start() error {
+	subs, err := submission.List(s.dir)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	s.cancel = cancel
+
+	s.wg.Add(1)
+	go s.dispatch(ctx)
+	for range s.Workers {
+		s.wg.Add(1)
+		go s.work(ctx)
+	}
+
+	s.pending.Add(len(subs))
+	go func() {
+		for _, sub := range subs {
+			s.enqueue(ctx, sub)
+		}
+	}()
+	return nil
+}
+
+// Close waits until all queued submissions are processed or ctx is done.
+// Unprocessed submissions remain in the spool directory.
+func (s *Spool) Close(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		s.pending.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-ctx.Done():
+	}
+
+	s.cancel()
+	s.wg.Wait()
+	return ctx.Err()
+}
+
+// Put stores the body durably and queues it for processing.
+func (s *Spool) Put(ctx context.Context, query url.Values, body io.Reader) (*submission.Submission, error) {
+	sub, err := submission.Create(s.dir, query, body)
+	if err != nil {
+		return nil, err
+	}
+
+	s.pending.Add(1)
+	select {
+	case s.queue <- sub:
+	case <-ctx.Done():
+		// The submission is picked up again on the next start.
+		s.pending.Done()
+	}
+	return sub, nil
+}
+
+func (s *Spool) enqueue(ctx context.Context, sub *submission.Submission) {
+	select {
+	case s.queue <- sub:
+	case <-ctx.Done():
+		s.pending.Done()
+	}
+}
+
+type keyed struct {
+	sub *submission.Submission
+	key string
+}
+
+// dispatch passes the queued submissions to the workers in order. A
+// submission whose key is being processed waits for the worker of that key.
+func (s *Spool) dispatch(ctx context.Context) {
+	defer s.wg.Done()
+	for {
+		select {
+		case sub := <-s.queue:
+			key := s.key(sub)
+			s.mu.Lock()
+			if list, busy := s.waiting[key]; busy && key != "" {
+				s.waiting[key] = append(list, sub)
+				s.mu.Unlock()
+				continue
+			}
+			if key != "" {
+				s.waiting[key] = nil
+			}
+			s.mu.Unlock()
+
+			select {
+			case s.ready <- keyed{sub, key}:
+			case <-ctx.Done():
+				return
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (s *Spool) key(sub *submission.Submission) string {
+	if s.Key == nil {
+		return ""
+	}
+	return s.Key(sub)
+}
+
+func (s *Spool) work(ctx context.Context) {
+	defer s.wg.Done()
+	for {
+		select {
+		case k := <-s.ready:
+			for sub := k.sub; sub != nil; sub = s.next(k.key) {
+				s.handle(ctx, sub)
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// next returns the submission that waits for key, or nil if there is none.
+func (s *Spool) next(key string) *submission.Submission {
+	if key == "" {
+		return nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	list := s.waiting[key]
+	if len(list) == 0 {
+		delete(s.waiting, key)
+		return nil
+	}
+	s.waiting[key] = list[1:]
+	return list[0]
+}
+
+type holdKey struct{}
+
+// hold is a submission that is being processed.
+type hold struct {
+	spool *Spool
+	sub   *submission.Submission
+	held  bool
+	once  sync.Once
+}
+
+// Hold keeps the submission that is processed with ctx in the spool, like
+// while its job is pending in memory. The submission is removed once the
+// returned function is called with nil, or moved to the "dead" subdirectory
+// if it is called with an error. A submission whose processing fails is moved
+// right away. Hold returns nil if ctx does not belong to a spool.
+func Hold(ctx context.Context) func(error) {
+	h, ok := ctx.Value(holdKey{}).(*hold)
+	if !ok {
+		return nil
+	}
+	h.held = true
+	return h.release
+}
+
+func (h *hold) release(err error) {
+	h.once.Do(func() {
+		h.spool.finish(h.sub, err)
+	})
+}
+
+func (s *Spool) handle(ctx context.Context, sub *submission.Submission) {
+	defer s.pending.Done()
+
+	h := &hold{spool: s, sub: sub}
+	err := s.process(context.WithValue(ctx, holdKey{}, h), sub)
+	if err != nil || !h.held {
+		h.release(err)
+	}
+}
+
+// finish removes a submission that was processed, or moves it to the "dead"
+// subdirectory if processing failed.
+func (s *Spool) finish(sub *submission.Submission, err error) {
+	if err == nil {
+		if err := sub.Remove(); err != nil {
+			log.Printf("spool %s: %v", sub.ID, err)
+		}
+		return
+	}
+
+	sub.Attempts++
+	sub.LastErr = err.Error()
+	log.Printf("spool %s: %v", sub.ID, err)
+
+	if err := sub.Save(); err != nil {
+		log.Printf("spool %s: %v", sub.ID, err)
+	}
+	if err := sub.MoveTo(s.DeadDir()); err != nil {
+		log.Printf("spool %s: %v", sub.ID, err)
+	}
+}
@@ -0,0 +1,176 @@
+// SPDX-FileCopyrightText: 2025 Daniel Pfeifer <daniel@pfeifer-mail.de>
+// SPDX-License-Identifier: ISC
+
+package spool
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/chorse-dev/cdash-proxy/submission"
+)
+
+func TestProcess(t *testing.T) {
+	dir := t.TempDir()
+	bodies := make(chan string, 1)
+	sp := New(dir, func(ctx context.Context, sub *submission.Submission) error {
+		f, err := sub.Open()
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		data, err := io.ReadAll(f)
+		bodies <- string(data)
+		return err
+	})
+	if err := sp.Start(); err != nil {
+		t.Fatal(err)
+	}
+
+	query := url.Values{"FileName": {"Build.xml"}}
+	if _, err := sp.Put(context.Background(), query, strings.NewReader("<Site/>")); err != nil {
+		t.Fatal(err)
+	}
+	if err := sp.Close(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if body := <-bodies; body != "<Site/>" {
+		t.Errorf("unexpected body %q", body)
+	}
+	if subs, _ := submission.List(dir); len(subs) != 0 {
+		t.Errorf("expected empty spool, got %d submissions", len(subs))
+	}
+}
+
+func TestDeadLetter(t *testing.T) {
+	dir := t.TempDir()
+	var attempts atomic.Int32
+	sp := New(dir, func(ctx context.Context, sub *submission.Submission) error {
+		attempts.Add(1)
+		return errors.New("parse error")
+	})
+	if err := sp.Start(); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := sp.Put(context.Background(), url.Values{}, strings.NewReader("")); err != nil {
+		t.Fatal(err)
+	}
+	if err := sp.Close(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if attempts.Load() != 1 {
+		t.Errorf("expected 1 attempt, got %d", attempts.Load())
+	}
+	dead, err := submission.List(sp.DeadDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(dead) != 1 || dead[0].LastErr != "parse error" {
+		t.Errorf("expected one dead submission, got %+v", dead)
+	}
+}
+
+func TestHold(t *testing.T) {
+	dir := t.TempDir()
+	releases := make(chan func(error), 2)
+	sp := New(dir, func(ctx context.Context, sub *submission.Submission) error {
+		releases <- Hold(ctx)
+		return nil
+	})
+	if err := sp.Start(); err != nil {
+		t.Fatal(err)
+	}
+
+	for range 2 {
+		if _, err := sp.Put(context.Background(), url.Values{}, strings.NewReader("")); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := sp.Close(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	// Held submissions survive a shutdown.
+	if subs, _ := submission.List(dir); len(subs) != 2 {
+		t.Fatalf("expected 2 held submissions, got %d", len(subs))
+	}
+
+	(<-releases)(nil)
+	(<-releases)(errors.New("job lost"))
+	if subs, _ := submission.List(dir); len(subs) != 0 {
+		t.Errorf("expected empty spool, got %d submissions", len(subs))
+	}
+	if dead, _ := submission.List(sp.DeadDir()); len(dead) != 1 || dead[0].LastErr != "job lost" {
+		t.Errorf("expected one dead submission, got %+v", dead)
+	}
+}
+
+func TestOrder(t *testing.T) {
+	var mu sync.Mutex
+	var order []string
+	sp := New(t.TempDir(), func(ctx context.Context, sub *submission.Submission) error {
+		name := sub.Query.Get("FileName")
+		if name == "Configure.xml" {
+			time.Sleep(10 * time.Millisecond)
+		}
+		mu.Lock()
+		order = append(order, name)
+		mu.Unlock()
+		return nil
+	})
+	sp.Key = func(sub *submission.Submission) string {
+		return sub.Query.Get("buildid")
+	}
+	if err := sp.Start(); err != nil {
+		t.Fatal(err)
+	}
+
+	names := []string{"Configure.xml", "Build.xml", "Test.xml", "Done.xml"}
+	for _, name := range names {
+		query := url.Values{"FileName": {name}, "buildid": {"job"}}
+		if _, err := sp.Put(context.Background(), query, strings.NewReader("")); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := sp.Close(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if !slices.Equal(order, names) {
+		t.Errorf("unexpected order %v", order)
+	}
+}
+
+func TestRecover(t *testing.T) {
+	dir := t.TempDir()
+	if _, err := submission.Create(dir, url.Values{}, strings.NewReader("")); err != nil {
+		t.Fatal(err)
+	}
+
+	var processed atomic.Int32
+	sp := New(dir, func(ctx context.Context, sub *submission.Submission) error {
+		processed.Add(1)
+		return nil
+	})
+	if err := sp.Start(); err != nil {
+		t.Fatal(err)
+	}
+	if err := sp.Close(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if processed.Load() != 1 {
+		t.Errorf("expected spooled submission to be processed")
+	}
+}
@@ -0,0 +1,300 @@
+// SPDX-FileCopyrightText: 2025 Daniel Pfeifer <daniel@pfeifer-mail.de>
+// SPDX-License-Identifier: ISC
+
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	"github.com/chorse-dev/cdash-proxy/model"
+)
+
+// Insert stores a job within a single transaction. A job that is already
+// known is updated, so the parts of a job may be inserted one after another.
+// A reprocessed job replaces the stored one if it is done, otherwise only the
+// parts that it carries.
+func (s *DB) Insert(ctx context.Context, job *model.Job) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if job.Reprocessed {
+		if err := deleteParts(ctx, tx, job); err != nil {
+			return err
+		}
+	}
+
+	hostID, err := insertHost(ctx, tx, job.Host)
+	if err != nil {
+		return err
+	}
+
+	if err := insertJob(ctx, tx, job, hostID); err != nil {
+		return err
+	}
+
+	// The commits are reported at once, so they replace the stored ones.
+	if len(job.Commits) != 0 {
+		if _, err := tx.ExecContext(ctx, "DELETE FROM commits WHERE job_id = ?", job.JobID); err != nil {
+			return err
+		}
+	}
+	for _, commit := range job.Commits {
+		if err := insertCommit(ctx, tx, job.JobID, &commit); err != nil {
+			return err
+		}
+	}
+
+	for _, cmd := range job.Commands {
+		if err := insertCommand(ctx, tx, job.JobID, &cmd); err != nil {
+			return err
+		}
+	}
+
+	for _, cov := range job.Coverage {
+		if err := insertCoverage(ctx, tx, job.JobID, &cov); err != nil {
+			return err
+		}
+	}
+
+	for _, file := range job.AttachedFiles {
+		if err := insertAttachment(ctx, tx, job.JobID, nil, &file); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// deleteParts deletes what a reprocessed job replaces: the whole job if it
+// is done, otherwise the commands of the roles it has, and its coverage and
+// attachments if it has any.
+func deleteParts(ctx context.Context, tx *sql.Tx, job *model.Job) error {
+	if job.Done {
+		_, err := tx.ExecContext(ctx, "DELETE FROM jobs WHERE job_id = ?", job.JobID)
+		return err
+	}
+
+	roles := map[string]bool{}
+	for _, cmd := range job.Commands {
+		roles[cmd.Role] = true
+	}
+	for role := range roles {
+		_, err := tx.ExecContext(ctx, "DELETE FROM commands WHERE job_id = ? AND role = ?", job.JobID, role)
+		if err != nil {
+			return err
+		}
+	}
+
+	if len(job.Coverage) != 0 {
+		if _, err := tx.ExecContext(ctx, "DELETE FROM coverage WHERE job_id = ?", job.JobID); err != nil {
+			return err
+		}
+	}
+	if len(job.AttachedFiles) != 0 {
+		_, err := tx.ExecContext(ctx, "DELETE FROM attachments WHERE job_id = ? AND command_id IS NULL", job.JobID)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Hosts are shared between jobs. A host that changed in any way, for example
+// by an OS update, is inserted as a new row.
+func insertHost(ctx context.Context, tx *sql.Tx, host *model.Host) (any, error) {
+	if host == nil {
+		return nil, nil
+	}
+
+	args := []any{
+		host.Site, host.Name,
+		host.CPU.Vendor, host.CPU.VendorID, host.CPU.FamilyID, host.CPU.ModelID, host.CPU.ModelName,
+		host.CPU.LogicalCores, host.CPU.PhysicalCores, host.CPU.CacheSize,
+		host.OS.Name, host.OS.Release, host.OS.Version, host.OS.Platform,
+		host.PhysicalMemory, host.VirtualMemory,
+	}
+
+	_, err := tx.ExecContext(ctx, `
+		INSERT INTO hosts (
+			site, name,
+			cpu_vendor, cpu_vendor_id, cpu_family_id, cpu_model_id, cpu_model_name,
+			cpu_logical, cpu_physical, cpu_cache_size,
+			os_name, os_release, os_version, os_platform,
+			physical_memory, virtual_memory
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT DO NOTHING`, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	var hostID int64
+	err = tx.QueryRowContext(ctx, `
+		SELECT host_id FROM hosts WHERE
+			site = ? AND name = ? AND
+			cpu_vendor = ? AND cpu_vendor_id = ? AND cpu_family_id = ? AND cpu_model_id = ? AND cpu_model_name = ? AND
+			cpu_logical = ? AND cpu_physical = ? AND cpu_cache_size = ? AND
+			os_name = ? AND os_release = ? AND os_version = ? AND os_platform = ? AND
+			physical_memory = ? AND virtual_memory = ?`, args...).Scan(&hostID)
+	return hostID, err
+}
+
+func insertJob(ctx context.Context, tx *sql.Tx, job *model.Job, hostID any) error {
+	site := ""
+	if job.Host != nil {
+		site = job.Host.Site
+	}
+
+	_, err := tx.ExecContext(ctx, `
+		INSERT INTO jobs (
+			job_id, project, build_name, build_group, change_id, prior_change_id, generator, site,
+			start_update_time, end_update_time,
+			start_configure_time, end_configure_time,
+			start_build_time, end_build_time,
+			start_test_time, end_test_time,
+			start_coverage_time, end_coverage_time,
+			start_memcheck_time, end_memcheck_time,
+			done, host_id, parser_version, reprocessed
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (job_id) DO UPDATE SET
+			project              = coalesce(nullif(excluded.project, ''), project),
+			build_name           = coalesce(nullif(excluded.build_name, ''), build_name),
+			build_group          = coalesce(nullif(excluded.build_group, ''), build_group),
+			change_id            = coalesce(nullif(excluded.change_id, ''), change_id),
+			prior_change_id      = coalesce(nullif(excluded.prior_change_id, ''), prior_change_id),
+			generator            = coalesce(nullif(excluded.generator, ''), generator),
+			site                 = coalesce(nullif(excluded.site, ''), site),
+			start_update_time    = coalesce(excluded.start_update_time, start_update_time),
+			end_update_time      = coalesce(excluded.end_update_time, end_update_time),
+			start_configure_time = coalesce(excluded.start_configure_time, start_configure_time),
+			end_configure_time   = coalesce(excluded.end_configure_time, end_configure_time),
+			start_build_time     = coalesce(excluded.start_build_time, start_build_time),
+			end_build_time       = coalesce(excluded.end_build_time, end_build_time),
+			start_test_time      = coalesce(excluded.start_test_time, start_test_time),
+			end_test_time        = coalesce(excluded.end_test_time, end_test_time),
+			start_coverage_time  = coalesce(excluded.start_coverage_time, start_coverage_time),
+			end_coverage_time    = coalesce(excluded.end_coverage_time, end_coverage_time),
+			start_memcheck_time  = coalesce(excluded.start_memcheck_time, start_memcheck_time),
+			end_memcheck_time    = coalesce(excluded.end_memcheck_time, end_memcheck_time),
+			done                 = max(excluded.done, done),
+			host_id              = coalesce(excluded.host_id, host_id),
+			parser_version       = max(excluded.parser_version, parser_version),
+			reprocessed          = max(excluded.reprocessed, reprocessed)`,
+		job.JobID, job.Project, job.BuildName, job.BuildGroup, job.ChangeID, job.PriorChangeID, job.Generator, site,
+		unixMilli(job.StartUpdateTime), unixMilli(job.EndUpdateTime),
+		unixMilli(job.StartConfigureTime), unixMilli(job.EndConfigureTime),
+		unixMilli(job.StartBuildTime), unixMilli(job.EndBuildTime),
+		unixMilli(job.StartTestTime), unixMilli(job.EndTestTime),
+		unixMilli(job.StartCoverageTime), unixMilli(job.EndCoverageTime),
+		unixMilli(job.StartMemcheckTime), unixMilli(job.EndMemcheckTime),
+		job.Done, hostID, job.ParserVersion, job.Reprocessed,
+	)
+	return err
+}
+
+func insertCommit(ctx context.Context, tx *sql.Tx, jobID string, commit *model.Commit) error {
+	_, err := tx.ExecContext(ctx, `
+		INSERT INTO commits (
+			job_id, revision, author, email, author_time,
+			committer, committer_email, commit_time, log, files
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		jobID, commit.Revision, commit.Author, commit.Email, unixMilli(commit.AuthorTime),
+		commit.Committer, commit.CommitterEmail, unixMilli(commit.CommitTime), commit.Log, toJSON(commit.Files),
+	)
+	return err
+}
+
+func insertCommand(ctx context.Context, tx *sql.Tx, jobID string, cmd *model.Command) error {
+	res, err := tx.ExecContext(ctx, `
+		INSERT INTO commands (
+			job_id, command_line, working_directory, result, role,
+			target, target_type, target_labels, start_time, duration,
+			outputs, output_sizes, source, language, test_name, test_status,
+			config, stdout, stderr, attributes, measurements
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		jobID, cmd.CommandLine, cmd.WorkingDirectory, cmd.Result, cmd.Role,
+		cmd.Target, cmd.TargetType, toJSON(cmd.TargetLabels), unixMilli(cmd.StartTime), cmd.Duration,
+		toJSON(cmd.Outputs), toJSON(cmd.OutputSizes), cmd.Source, cmd.Language, cmd.TestName, cmd.TestStatus,
+		cmd.Config, cmd.StdOut, cmd.StdErr, toJSON(cmd.Attributes), toJSON(cmd.Measurements),
+	)
+	if err != nil {
+		return err
+	}
+
+	commandID, err := res.LastInsertId()
+	if err != nil {
+		return err
+	}
+
+	for _, diag := range cmd.Diagnostics {
+		_, err := tx.ExecContext(ctx, `
+			INSERT INTO diagnostics (
+				command_id, file_path, line, column, type, message, option,
+				fingerprint, suppressed, suppressed_by, related
+			) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+			commandID, diag.FilePath, diag.Line, diag.Column, diag.Type, diag.Message, diag.Option,
+			diag.Fingerprint, diag.Suppressed, diag.SuppressedBy, toJSON(diag.Related),
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, file := range cmd.AttachedFiles {
+		if err := insertAttachment(ctx, tx, jobID, commandID, &file); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func insertCoverage(ctx context.Context, tx *sql.Tx, jobID string, cov *model.Coverage) error {
+	_, err := tx.ExecContext(ctx, `
+		INSERT INTO coverage (
+			job_id, file_path, lines,
+			lines_tested, lines_untested,
+			branches_tested, branches_untested,
+			functions_tested, functions_untested,
+			labels
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		jobID, cov.FilePath, toJSON(cov.Lines),
+		cov.LinesTested, cov.LinesUntested,
+		cov.BranchesTested, cov.BranchesUntested,
+		cov.FunctionsTested, cov.FunctionsUntested,
+		toJSON(cov.Labels),
+	)
+	return err
+}
+
+// Attachments of the job itself have no command.
+func insertAttachment(ctx context.Context, tx *sql.Tx, jobID string, commandID any, file *model.AttachedFile) error {
+	_, err := tx.ExecContext(ctx, `
+		INSERT INTO attachments (
+			job_id, command_id, name, filename, type, content
+		) VALUES (?, ?, ?, ?, ?, ?)`,
+		jobID, commandID, file.Name, file.Filename, file.Type, file.Content,
+	)
+	return err
+}
+
+func unixMilli(t *time.Time) any {
+	if t == nil {
+		return nil
+	}
+	return t.UnixMilli()
+}
+
+// Slices and maps are stored as JSON text.
+func toJSON[T any](v T) any {
+	data, err := json.Marshal(v)
+	if err != nil || string(data) == "null" {
+		return nil
+	}
+	return string(data)
+}
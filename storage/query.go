@@ -0,0 +1,551 @@
+// SPDX-FileCopyrightText: 2025 Daniel Pfeifer <daniel@pfeifer-mail.de>
+// SPDX-License-Identifier: ISC
+
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/chorse-dev/cdash-proxy/model"
+)
+
+var ErrNotFound = errors.New("not found")
+
+type JobFilter struct {
+	Project   string
+	BuildName string
+	Since     time.Time
+	Limit     int
+}
+
+type CommandFilter struct {
+	Role       string
+	TestStatus string
+}
+
+type DiagnosticFilter struct {
+	JobID    string
+	FilePath string
+	Type     string
+
+	Fingerprint string
+
+	// Suppressed, if set, selects only suppressed or only unsuppressed
+	// diagnostics.
+	Suppressed *bool
+
+	Limit int
+}
+
+const jobColumns = `
+	j.job_id, j.project, j.build_name, j.build_group, j.change_id, j.prior_change_id, j.generator,
+	j.start_update_time, j.end_update_time,
+	j.start_configure_time, j.end_configure_time,
+	j.start_build_time, j.end_build_time,
+	j.start_test_time, j.end_test_time,
+	j.start_coverage_time, j.end_coverage_time,
+	j.start_memcheck_time, j.end_memcheck_time,
+	j.done, j.parser_version, j.reprocessed,
+	h.site, h.name,
+	h.cpu_vendor, h.cpu_vendor_id, h.cpu_family_id, h.cpu_model_id, h.cpu_model_name,
+	h.cpu_logical, h.cpu_physical, h.cpu_cache_size,
+	h.os_name, h.os_release, h.os_version, h.os_platform,
+	h.physical_memory, h.virtual_memory`
+
+const jobTables = `jobs j LEFT JOIN hosts h ON h.host_id = j.host_id`
+
+// The first time of a job that is known, used to order jobs.
+const jobTime = `coalesce(
+	j.start_update_time, j.start_configure_time, j.start_build_time,
+	j.start_test_time, j.start_coverage_time, j.start_memcheck_time)`
+
+// Jobs returns the jobs matching the filter, newest first. The jobs do not
+// include commands, coverage, or attached files.
+func (s *DB) Jobs(ctx context.Context, f JobFilter) ([]model.Job, error) {
+	var where []string
+	var args []any
+	if f.Project != "" {
+		where = append(where, "j.project = ?")
+		args = append(args, f.Project)
+	}
+	if f.BuildName != "" {
+		where = append(where, "j.build_name = ?")
+		args = append(args, f.BuildName)
+	}
+	if !f.Since.IsZero() {
+		where = append(where, jobTime+" >= ?")
+		args = append(args, f.Since.UnixMilli())
+	}
+
+	query := "SELECT " + jobColumns + " FROM " + jobTables
+	if len(where) != 0 {
+		query += " WHERE " + strings.Join(where, " AND ")
+	}
+	query += " ORDER BY " + jobTime + " DESC"
+	if f.Limit > 0 {
+		query += " LIMIT ?"
+		args = append(args, f.Limit)
+	}
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	jobs := []model.Job{}
+	for rows.Next() {
+		job, err := scanJob(rows)
+		if err != nil {
+			return nil, err
+		}
+		jobs = append(jobs, *job)
+	}
+	return jobs, rows.Err()
+}
+
+// Job returns the complete job with the given ID.
+func (s *DB) Job(ctx context.Context, jobID string) (*model.Job, error) {
+	row := s.db.QueryRowContext(ctx,
+		"SELECT "+jobColumns+" FROM "+jobTables+" WHERE j.job_id = ?", jobID)
+	job, err := scanJob(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if job.Commits, err = s.commits(ctx, jobID); err != nil {
+		return nil, err
+	}
+	if job.Commands, err = s.Commands(ctx, jobID, CommandFilter{}); err != nil {
+		return nil, err
+	}
+	if job.Coverage, err = s.coverage(ctx, jobID); err != nil {
+		return nil, err
+	}
+	if job.AttachedFiles, err = s.attachments(ctx, "job_id = ? AND command_id IS NULL", jobID); err != nil {
+		return nil, err
+	}
+	return job, nil
+}
+
+// Previous returns the complete job that precedes the given job in the same
+// project, site and build name, or ErrNotFound.
+func (s *DB) Previous(ctx context.Context, job *model.Job) (*model.Job, error) {
+	var site string
+	if job.Host != nil {
+		site = job.Host.Site
+	}
+
+	var t *time.Time
+	for _, t = range []*time.Time{
+		job.StartUpdateTime, job.StartConfigureTime, job.StartBuildTime,
+		job.StartTestTime, job.StartCoverageTime, job.StartMemcheckTime,
+	} {
+		if t != nil {
+			break
+		}
+	}
+	if t == nil {
+		return nil, ErrNotFound
+	}
+
+	var jobID string
+	err := s.db.QueryRowContext(ctx, `
+		SELECT j.job_id FROM `+jobTables+`
+		WHERE j.project = ? AND j.build_name = ? AND coalesce(h.site, '') = ?
+			AND j.job_id != ? AND j.done AND `+jobTime+` < ?
+		ORDER BY `+jobTime+` DESC LIMIT 1`,
+		job.Project, job.BuildName, site, job.JobID, t.UnixMilli(),
+	).Scan(&jobID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return s.Job(ctx, jobID)
+}
+
+// JobExists reports whether a job with the given ID is stored.
+func (s *DB) JobExists(ctx context.Context, jobID string) (bool, error) {
+	err := s.db.QueryRowContext(ctx, "SELECT 1 FROM jobs WHERE job_id = ?", jobID).Scan(new(int))
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// Commands returns the commands of a job, including their diagnostics and
+// attached files.
+func (s *DB) Commands(ctx context.Context, jobID string, f CommandFilter) ([]model.Command, error) {
+	where := "job_id = ?"
+	args := []any{jobID}
+	if f.Role != "" {
+		where += " AND role = ?"
+		args = append(args, f.Role)
+	}
+	if f.TestStatus != "" {
+		where += " AND test_status = ?"
+		args = append(args, f.TestStatus)
+	}
+
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT
+			command_id, command_line, working_directory, result, role,
+			target, target_type, target_labels, start_time, duration,
+			outputs, output_sizes, source, language, test_name, test_status,
+			config, stdout, stderr, attributes, measurements
+		FROM commands WHERE `+where+` ORDER BY command_id`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []int64
+	cmds := []model.Command{}
+	for rows.Next() {
+		var id int64
+		var cmd model.Command
+		var startTime sql.NullInt64
+		var labels, outputs, outputSizes, attributes, measurements sql.NullString
+		err := rows.Scan(
+			&id, &cmd.CommandLine, &cmd.WorkingDirectory, &cmd.Result, &cmd.Role,
+			&cmd.Target, &cmd.TargetType, &labels, &startTime, &cmd.Duration,
+			&outputs, &outputSizes, &cmd.Source, &cmd.Language, &cmd.TestName, &cmd.TestStatus,
+			&cmd.Config, &cmd.StdOut, &cmd.StdErr, &attributes, &measurements,
+		)
+		if err != nil {
+			return nil, err
+		}
+		cmd.StartTime = fromUnixMilli(startTime)
+		fromJSON(labels, &cmd.TargetLabels)
+		fromJSON(outputs, &cmd.Outputs)
+		fromJSON(outputSizes, &cmd.OutputSizes)
+		fromJSON(attributes, &cmd.Attributes)
+		fromJSON(measurements, &cmd.Measurements)
+		ids = append(ids, id)
+		cmds = append(cmds, cmd)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	rows.Close()
+
+	// The diagnostics and attached files of all commands are read at once.
+	commands := "command_id IN (SELECT command_id FROM commands WHERE " + where + ")"
+	diags, err := s.commandDiagnostics(ctx, commands, args...)
+	if err != nil {
+		return nil, err
+	}
+	files, err := s.commandAttachments(ctx, commands, args...)
+	if err != nil {
+		return nil, err
+	}
+	for i, id := range ids {
+		cmds[i].Diagnostics = diags[id]
+		cmds[i].AttachedFiles = files[id]
+	}
+	return cmds, nil
+}
+
+// Diagnostics returns the diagnostics matching the filter across all jobs.
+func (s *DB) Diagnostics(ctx context.Context, f DiagnosticFilter) ([]model.Diagnostic, error) {
+	where := []string{"1"}
+	var args []any
+	if f.JobID != "" {
+		where = append(where, "command_id IN (SELECT command_id FROM commands WHERE job_id = ?)")
+		args = append(args, f.JobID)
+	}
+	if f.FilePath != "" {
+		where = append(where, "file_path = ?")
+		args = append(args, f.FilePath)
+	}
+	if f.Type != "" {
+		where = append(where, "type = ?")
+		args = append(args, f.Type)
+	}
+	if f.Fingerprint != "" {
+		where = append(where, "fingerprint = ?")
+		args = append(args, f.Fingerprint)
+	}
+	if f.Suppressed != nil {
+		where = append(where, "suppressed = ?")
+		args = append(args, *f.Suppressed)
+	}
+	return s.diagnostics(ctx, strings.Join(where, " AND "), f.Limit, args...)
+}
+
+const diagnosticColumns = `file_path, line, column, type, message, option,
+	fingerprint, suppressed, suppressed_by, related`
+
+// diagnostics returns the diagnostics matching where, at most limit unless
+// limit is zero.
+func (s *DB) diagnostics(ctx context.Context, where string, limit int, args ...any) ([]model.Diagnostic, error) {
+	query := "SELECT " + diagnosticColumns + " FROM diagnostics WHERE " + where + " ORDER BY diagnostic_id"
+	if limit > 0 {
+		query += " LIMIT ?"
+		args = append(args, limit)
+	}
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var diags []model.Diagnostic
+	for rows.Next() {
+		d, err := scanDiagnostic(rows)
+		if err != nil {
+			return nil, err
+		}
+		diags = append(diags, d)
+	}
+	return diags, rows.Err()
+}
+
+// commandDiagnostics returns the diagnostics matching where by command.
+func (s *DB) commandDiagnostics(ctx context.Context, where string, args ...any) (map[int64][]model.Diagnostic, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT command_id, `+diagnosticColumns+`
+		FROM diagnostics WHERE `+where+` ORDER BY diagnostic_id`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	diags := map[int64][]model.Diagnostic{}
+	for rows.Next() {
+		var id int64
+		d, err := scanDiagnostic(rows, &id)
+		if err != nil {
+			return nil, err
+		}
+		diags[id] = append(diags[id], d)
+	}
+	return diags, rows.Err()
+}
+
+// scanDiagnostic scans the diagnostic columns, preceded by dest.
+func scanDiagnostic(rows *sql.Rows, dest ...any) (model.Diagnostic, error) {
+	var d model.Diagnostic
+	var related sql.NullString
+	dest = append(dest, &d.FilePath, &d.Line, &d.Column, &d.Type, &d.Message, &d.Option,
+		&d.Fingerprint, &d.Suppressed, &d.SuppressedBy, &related)
+	if err := rows.Scan(dest...); err != nil {
+		return d, err
+	}
+	fromJSON(related, &d.Related)
+	return d, nil
+}
+
+func (s *DB) attachments(ctx context.Context, where string, args ...any) ([]model.AttachedFile, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT name, filename, type, content
+		FROM attachments WHERE `+where+` ORDER BY attachment_id`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var files []model.AttachedFile
+	for rows.Next() {
+		var f model.AttachedFile
+		if err := rows.Scan(&f.Name, &f.Filename, &f.Type, &f.Content); err != nil {
+			return nil, err
+		}
+		files = append(files, f)
+	}
+	return files, rows.Err()
+}
+
+// commandAttachments returns the attached files matching where by command.
+func (s *DB) commandAttachments(ctx context.Context, where string, args ...any) (map[int64][]model.AttachedFile, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT command_id, name, filename, type, content
+		FROM attachments WHERE `+where+` ORDER BY attachment_id`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	files := map[int64][]model.AttachedFile{}
+	for rows.Next() {
+		var id int64
+		var f model.AttachedFile
+		if err := rows.Scan(&id, &f.Name, &f.Filename, &f.Type, &f.Content); err != nil {
+			return nil, err
+		}
+		files[id] = append(files[id], f)
+	}
+	return files, rows.Err()
+}
+
+func (s *DB) commits(ctx context.Context, jobID string) ([]model.Commit, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT
+			revision, author, email, author_time,
+			committer, committer_email, commit_time, log, files
+		FROM commits WHERE job_id = ? ORDER BY commit_id`, jobID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var commits []model.Commit
+	for rows.Next() {
+		var c model.Commit
+		var authorTime, commitTime sql.NullInt64
+		var files sql.NullString
+		err := rows.Scan(&c.Revision, &c.Author, &c.Email, &authorTime,
+			&c.Committer, &c.CommitterEmail, &commitTime, &c.Log, &files)
+		if err != nil {
+			return nil, err
+		}
+		c.AuthorTime = fromUnixMilli(authorTime)
+		c.CommitTime = fromUnixMilli(commitTime)
+		fromJSON(files, &c.Files)
+		commits = append(commits, c)
+	}
+	return commits, rows.Err()
+}
+
+func (s *DB) coverage(ctx context.Context, jobID string) ([]model.Coverage, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT
+			file_path, lines,
+			lines_tested, lines_untested,
+			branches_tested, branches_untested,
+			functions_tested, functions_untested,
+			labels
+		FROM coverage WHERE job_id = ? ORDER BY coverage_id`, jobID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var files []model.Coverage
+	for rows.Next() {
+		var c model.Coverage
+		var lines, labels sql.NullString
+		var counts [6]sql.NullInt64
+		err := rows.Scan(&c.FilePath, &lines,
+			&counts[0], &counts[1], &counts[2], &counts[3], &counts[4], &counts[5],
+			&labels)
+		if err != nil {
+			return nil, err
+		}
+		fromJSON(lines, &c.Lines)
+		fromJSON(labels, &c.Labels)
+		c.LinesTested = fromNullInt(counts[0])
+		c.LinesUntested = fromNullInt(counts[1])
+		c.BranchesTested = fromNullInt(counts[2])
+		c.BranchesUntested = fromNullInt(counts[3])
+		c.FunctionsTested = fromNullInt(counts[4])
+		c.FunctionsUntested = fromNullInt(counts[5])
+		files = append(files, c)
+	}
+	return files, rows.Err()
+}
+
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+func scanJob(row scanner) (*model.Job, error) {
+	var job model.Job
+	var times [12]sql.NullInt64
+	var site, name sql.NullString
+	var h struct {
+		vendor, vendorID, modelName         sql.NullString
+		familyID, modelID                   sql.NullInt64
+		logical, physical, cacheSize        sql.NullInt64
+		osName, osRelease, osVersion, osPlt sql.NullString
+		physicalMemory, virtualMemory       sql.NullInt64
+	}
+
+	err := row.Scan(
+		&job.JobID, &job.Project, &job.BuildName, &job.BuildGroup, &job.ChangeID, &job.PriorChangeID, &job.Generator,
+		&times[0], &times[1], &times[2], &times[3], &times[4], &times[5],
+		&times[6], &times[7], &times[8], &times[9], &times[10], &times[11],
+		&job.Done, &job.ParserVersion, &job.Reprocessed,
+		&site, &name,
+		&h.vendor, &h.vendorID, &h.familyID, &h.modelID, &h.modelName,
+		&h.logical, &h.physical, &h.cacheSize,
+		&h.osName, &h.osRelease, &h.osVersion, &h.osPlt,
+		&h.physicalMemory, &h.virtualMemory,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	job.StartUpdateTime = fromUnixMilli(times[0])
+	job.EndUpdateTime = fromUnixMilli(times[1])
+	job.StartConfigureTime = fromUnixMilli(times[2])
+	job.EndConfigureTime = fromUnixMilli(times[3])
+	job.StartBuildTime = fromUnixMilli(times[4])
+	job.EndBuildTime = fromUnixMilli(times[5])
+	job.StartTestTime = fromUnixMilli(times[6])
+	job.EndTestTime = fromUnixMilli(times[7])
+	job.StartCoverageTime = fromUnixMilli(times[8])
+	job.EndCoverageTime = fromUnixMilli(times[9])
+	job.StartMemcheckTime = fromUnixMilli(times[10])
+	job.EndMemcheckTime = fromUnixMilli(times[11])
+
+	if site.Valid {
+		job.Host = &model.Host{
+			Site:           site.String,
+			Name:           name.String,
+			PhysicalMemory: int(h.physicalMemory.Int64),
+			VirtualMemory:  int(h.virtualMemory.Int64),
+		}
+		job.Host.CPU = model.CPU{
+			Vendor:        h.vendor.String,
+			VendorID:      h.vendorID.String,
+			FamilyID:      int(h.familyID.Int64),
+			ModelID:       int(h.modelID.Int64),
+			ModelName:     h.modelName.String,
+			LogicalCores:  int(h.logical.Int64),
+			PhysicalCores: int(h.physical.Int64),
+			CacheSize:     int(h.cacheSize.Int64),
+		}
+		job.Host.OS = model.OS{
+			Name:     h.osName.String,
+			Release:  h.osRelease.String,
+			Version:  h.osVersion.String,
+			Platform: h.osPlt.String,
+		}
+	}
+
+	return &job, nil
+}
+
+func fromUnixMilli(v sql.NullInt64) *time.Time {
+	if !v.Valid {
+		return nil
+	}
+	t := time.UnixMilli(v.Int64)
+	return &t
+}
+
+func fromNullInt(v sql.NullInt64) *int {
+	if !v.Valid {
+		return nil
+	}
+	i := int(v.Int64)
+	return &i
+}
+
+func fromJSON(v sql.NullString, dst any) {
+	if v.Valid {
+		json.Unmarshal([]byte(v.String), dst)
+	}
+}
@@ -0,0 +1,202 @@
+// SPDX-FileCopyrightText: 2025 Daniel Pfeifer <daniel@pfeifer-mail.de>
+// SPDX-License-Identifier: ISC
+
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+// Each migration brings the schema from version i to version i+1. The
+// current version is kept in PRAGMA user_version. Never edit a migration that
+// was released, append a new one instead.
+var migrations = []string{`
+CREATE TABLE IF NOT EXISTS jobs (
+	job_id               TEXT PRIMARY KEY,
+	project              TEXT NOT NULL DEFAULT '',
+	build_name           TEXT NOT NULL DEFAULT '',
+	build_group          TEXT NOT NULL DEFAULT '',
+	change_id            TEXT NOT NULL DEFAULT '',
+	generator            TEXT NOT NULL DEFAULT '',
+	site                 TEXT NOT NULL DEFAULT '',
+	start_update_time    INTEGER,
+	end_update_time      INTEGER,
+	start_configure_time INTEGER,
+	end_configure_time   INTEGER,
+	start_build_time     INTEGER,
+	end_build_time       INTEGER,
+	start_test_time      INTEGER,
+	end_test_time        INTEGER,
+	start_coverage_time  INTEGER,
+	end_coverage_time    INTEGER,
+	start_memcheck_time  INTEGER,
+	end_memcheck_time    INTEGER,
+	done                 INTEGER NOT NULL DEFAULT 0
+);
+
+CREATE TABLE IF NOT EXISTS commands (
+	command_id        INTEGER PRIMARY KEY,
+	job_id            TEXT NOT NULL REFERENCES jobs(job_id) ON DELETE CASCADE,
+	command_line      TEXT NOT NULL,
+	working_directory TEXT NOT NULL,
+	result            INTEGER NOT NULL,
+	role              TEXT NOT NULL,
+	target            TEXT NOT NULL,
+	target_type       TEXT NOT NULL,
+	target_labels     TEXT,
+	start_time        INTEGER,
+	duration          INTEGER NOT NULL,
+	outputs           TEXT,
+	output_sizes      TEXT,
+	source            TEXT NOT NULL,
+	language          TEXT NOT NULL,
+	test_name         TEXT NOT NULL,
+	test_status       TEXT NOT NULL,
+	config            TEXT NOT NULL,
+	stdout            TEXT NOT NULL,
+	stderr            TEXT NOT NULL,
+	attributes        TEXT,
+	measurements      TEXT
+);
+
+CREATE INDEX IF NOT EXISTS commands_job_id ON commands(job_id);
+
+CREATE TABLE IF NOT EXISTS diagnostics (
+	diagnostic_id INTEGER PRIMARY KEY,
+	command_id    INTEGER NOT NULL REFERENCES commands(command_id) ON DELETE CASCADE,
+	file_path     TEXT NOT NULL,
+	line          INTEGER NOT NULL,
+	column        INTEGER NOT NULL,
+	type          TEXT NOT NULL,
+	message       TEXT NOT NULL,
+	option        TEXT NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS diagnostics_command_id ON diagnostics(command_id);
+CREATE INDEX IF NOT EXISTS diagnostics_file_path ON diagnostics(file_path);
+`, `
+CREATE TABLE hosts (
+	host_id          INTEGER PRIMARY KEY,
+	site             TEXT NOT NULL,
+	name             TEXT NOT NULL,
+	cpu_vendor       TEXT NOT NULL,
+	cpu_vendor_id    TEXT NOT NULL,
+	cpu_family_id    INTEGER NOT NULL,
+	cpu_model_id     INTEGER NOT NULL,
+	cpu_model_name   TEXT NOT NULL,
+	cpu_logical      INTEGER NOT NULL,
+	cpu_physical     INTEGER NOT NULL,
+	cpu_cache_size   INTEGER NOT NULL,
+	os_name          TEXT NOT NULL,
+	os_release       TEXT NOT NULL,
+	os_version       TEXT NOT NULL,
+	os_platform      TEXT NOT NULL,
+	physical_memory  INTEGER NOT NULL,
+	virtual_memory   INTEGER NOT NULL,
+	UNIQUE (
+		site, name,
+		cpu_vendor, cpu_vendor_id, cpu_family_id, cpu_model_id, cpu_model_name,
+		cpu_logical, cpu_physical, cpu_cache_size,
+		os_name, os_release, os_version, os_platform,
+		physical_memory, virtual_memory
+	)
+);
+
+ALTER TABLE jobs ADD COLUMN host_id INTEGER REFERENCES hosts(host_id);
+
+CREATE INDEX jobs_project_build_name ON jobs(project, build_name);
+
+CREATE TABLE coverage (
+	coverage_id        INTEGER PRIMARY KEY,
+	job_id             TEXT NOT NULL REFERENCES jobs(job_id) ON DELETE CASCADE,
+	file_path          TEXT NOT NULL,
+	lines              TEXT,
+	lines_tested       INTEGER,
+	lines_untested     INTEGER,
+	branches_tested    INTEGER,
+	branches_untested  INTEGER,
+	functions_tested   INTEGER,
+	functions_untested INTEGER,
+	labels             TEXT
+);
+
+CREATE INDEX coverage_job_id ON coverage(job_id);
+
+CREATE TABLE attachments (
+	attachment_id INTEGER PRIMARY KEY,
+	job_id        TEXT NOT NULL REFERENCES jobs(job_id) ON DELETE CASCADE,
+	command_id    INTEGER REFERENCES commands(command_id) ON DELETE CASCADE,
+	name          TEXT NOT NULL,
+	filename      TEXT NOT NULL,
+	type          TEXT NOT NULL,
+	content       BLOB
+);
+
+CREATE INDEX attachments_job_id ON attachments(job_id);
+CREATE INDEX attachments_command_id ON attachments(command_id);
+`, `
+ALTER TABLE jobs ADD COLUMN parser_version INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE jobs ADD COLUMN reprocessed INTEGER NOT NULL DEFAULT 0;
+`, `
+ALTER TABLE diagnostics ADD COLUMN suppressed INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE diagnostics ADD COLUMN suppressed_by TEXT NOT NULL DEFAULT '';
+`, `
+ALTER TABLE diagnostics ADD COLUMN fingerprint TEXT NOT NULL DEFAULT '';
+CREATE INDEX diagnostics_fingerprint ON diagnostics(fingerprint);
+`, `
+ALTER TABLE diagnostics ADD COLUMN related TEXT;
+`, `
+ALTER TABLE jobs ADD COLUMN prior_change_id TEXT NOT NULL DEFAULT '';
+
+CREATE TABLE commits (
+	commit_id       INTEGER PRIMARY KEY,
+	job_id          TEXT NOT NULL REFERENCES jobs(job_id) ON DELETE CASCADE,
+	revision        TEXT NOT NULL,
+	author          TEXT NOT NULL,
+	email           TEXT NOT NULL,
+	author_time     INTEGER,
+	committer       TEXT NOT NULL,
+	committer_email TEXT NOT NULL,
+	commit_time     INTEGER,
+	log             TEXT NOT NULL,
+	files           TEXT
+);
+
+CREATE INDEX commits_job_id ON commits(job_id);
+CREATE INDEX commits_revision ON commits(revision);
+CREATE INDEX commits_email ON commits(email);
+`}
+
+func migrate(ctx context.Context, db *sql.DB) error {
+	var version int
+	if err := db.QueryRowContext(ctx, "PRAGMA user_version").Scan(&version); err != nil {
+		return err
+	}
+
+	if version > len(migrations) {
+		return fmt.Errorf("database schema version %d is newer than supported version %d",
+			version, len(migrations))
+	}
+
+	for ; version < len(migrations); version++ {
+		tx, err := db.BeginTx(ctx, nil)
+		if err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx, migrations[version]); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("migration %d: %w", version+1, err)
+		}
+		if _, err := tx.ExecContext(ctx, fmt.Sprintf("PRAGMA user_version = %d", version+1)); err != nil {
+			tx.Rollback()
+			return err
+		}
+		if err := tx.Commit(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
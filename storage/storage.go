@@ -0,0 +1,50 @@
+// SPDX-FileCopyrightText: 2025 Daniel Pfeifer <daniel@pfeifer-mail.de>
+// SPDX-License-Identifier: ISC
+
+package storage
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/chorse-dev/cdash-proxy/model"
+	_ "modernc.org/sqlite"
+)
+
+// DB stores jobs in an embedded SQLite database using the commands and
+// diagnostics table split described in the README.
+type DB struct {
+	db *sql.DB
+}
+
+// Open opens the database at path, creating it if necessary, and migrates it
+// to the current schema.
+func Open(path string) (*DB, error) {
+	db, err := sql.Open("sqlite", path+"?_pragma=foreign_keys(1)&_pragma=busy_timeout(5000)")
+	if err != nil {
+		return nil, err
+	}
+
+	// SQLite does not support concurrent writers.
+	db.SetMaxOpenConns(1)
+
+	if err := migrate(context.Background(), db); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return &DB{db: db}, nil
+}
+
+func (s *DB) Close() error {
+	return s.db.Close()
+}
+
+// Store has the signature of web.HandlerFunc.
+func (s *DB) Store(ctx context.Context, job *model.Job) error {
+	return s.Insert(ctx, job)
+}
+
+func (s *DB) Ping(ctx context.Context) error {
+	return s.db.PingContext(ctx)
+}
@@ -0,0 +1,307 @@
+// SPDX-FileCopyrightText: 2025 Daniel Pfeifer <daniel@pfeifer-mail.de>
+// SPDX-License-Identifier: ISC
+
+package storage
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/chorse-dev/cdash-proxy/ctestxml"
+	"github.com/chorse-dev/cdash-proxy/model"
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+)
+
+func openTestDB(t *testing.T) *DB {
+	db, err := Open(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+func insertTestdata(t *testing.T, db *DB) {
+	xmlFiles, err := filepath.Glob("../ctestxml/testdata/*.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, xmlFile := range xmlFiles {
+		file, err := os.Open(xmlFile)
+		if err != nil {
+			t.Fatal(err)
+		}
+		job, err := ctestxml.Parse(file, "Example")
+		file.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := db.Insert(context.Background(), job); err != nil {
+			t.Fatalf("%s: %v", xmlFile, err)
+		}
+	}
+}
+
+func count(t *testing.T, db *DB, table string) int {
+	var n int
+	if err := db.db.QueryRow("SELECT count(*) FROM " + table).Scan(&n); err != nil {
+		t.Fatal(err)
+	}
+	return n
+}
+
+func TestInsert(t *testing.T) {
+	db := openTestDB(t)
+	insertTestdata(t, db)
+
+	for _, table := range []string{"jobs", "hosts", "commands", "diagnostics", "coverage", "attachments"} {
+		if count(t, db, table) == 0 {
+			t.Errorf("expected rows in table %s", table)
+		}
+	}
+
+	var done bool
+	err := db.db.QueryRow("SELECT done FROM jobs WHERE job_id = ?", "4e5a4b59fc4badd8ec47227aa4514ba1").Scan(&done)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !done {
+		t.Error("expected job to be done")
+	}
+}
+
+func TestMigrate(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.db")
+	for range 2 {
+		db, err := Open(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var version int
+		if err := db.db.QueryRow("PRAGMA user_version").Scan(&version); err != nil {
+			t.Fatal(err)
+		}
+		if version != len(migrations) {
+			t.Errorf("expected version %d, got %d", len(migrations), version)
+		}
+		db.Close()
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	db := openTestDB(t)
+
+	file, err := os.Open("../ctestxml/testdata/Test.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	expected, err := ctestxml.Parse(file, "Example")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Insert(context.Background(), expected); err != nil {
+		t.Fatal(err)
+	}
+
+	actual, err := db.Job(context.Background(), expected.JobID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(actual.Commands) != len(expected.Commands) {
+		t.Fatalf("expected %d commands, got %d", len(expected.Commands), len(actual.Commands))
+	}
+	for i := range actual.Commands {
+		if actual.Commands[i].TestName != expected.Commands[i].TestName ||
+			actual.Commands[i].StdOut != expected.Commands[i].StdOut {
+			t.Errorf("command %d differs", i)
+		}
+	}
+	if *actual.Host != *expected.Host {
+		t.Errorf("host differs: %+v", actual.Host)
+	}
+
+	if exists, err := db.JobExists(context.Background(), expected.JobID); !exists || err != nil {
+		t.Errorf("expected job to exist, got %v, %v", exists, err)
+	}
+	if exists, err := db.JobExists(context.Background(), "unknown"); exists || err != nil {
+		t.Errorf("expected unknown job not to exist, got %v, %v", exists, err)
+	}
+	if _, err := db.Job(context.Background(), "unknown"); err != ErrNotFound {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+}
+
+func TestRelatedLocations(t *testing.T) {
+	db := openTestDB(t)
+
+	file, err := os.Open("../ctestxml/testdata/Upload-SARIF.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	expected, err := ctestxml.Parse(file, "Example")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Insert(context.Background(), expected); err != nil {
+		t.Fatal(err)
+	}
+
+	actual, err := db.Job(context.Background(), expected.JobID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(expected.Commands[0].Diagnostics, actual.Commands[0].Diagnostics); diff != "" {
+		t.Errorf("mismatch (-expected +actual):\n%s", diff)
+	}
+}
+
+func TestCommits(t *testing.T) {
+	db := openTestDB(t)
+
+	file, err := os.Open("../ctestxml/testdata/Update-Files.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	expected, err := ctestxml.Parse(file, "Example")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Inserting the commits again replaces them.
+	for range 2 {
+		if err := db.Insert(context.Background(), expected); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	actual, err := db.Job(context.Background(), expected.JobID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if actual.PriorChangeID != expected.PriorChangeID {
+		t.Errorf("expected prior change %q, got %q", expected.PriorChangeID, actual.PriorChangeID)
+	}
+	if diff := cmp.Diff(expected.Commits, actual.Commits, cmpopts.EquateApproxTime(0)); diff != "" {
+		t.Errorf("mismatch (-expected +actual):\n%s", diff)
+	}
+}
+
+func parseTestfile(t *testing.T, name string) *model.Job {
+	file, err := os.Open("../ctestxml/testdata/" + name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	job, err := ctestxml.Parse(file, "Example")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return job
+}
+
+func TestReprocessed(t *testing.T) {
+	db := openTestDB(t)
+	ctx := context.Background()
+
+	var jobID string
+	for _, name := range []string{"Configure.xml", "Build.xml", "Test.xml", "Done.xml"} {
+		job := parseTestfile(t, name)
+		jobID = job.JobID
+		if err := db.Insert(ctx, job); err != nil {
+			t.Fatal(err)
+		}
+	}
+	complete, err := db.Job(ctx, jobID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A partial job replaces the commands of its roles only.
+	partial := parseTestfile(t, "Test.xml")
+	partial.Reprocessed = true
+	if err := db.Insert(ctx, partial); err != nil {
+		t.Fatal(err)
+	}
+	actual, err := db.Job(ctx, jobID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !actual.Done || !actual.Reprocessed || actual.StartConfigureTime == nil {
+		t.Errorf("job was not updated: %+v", actual)
+	}
+	roles := func(job *model.Job) map[string]int {
+		m := map[string]int{}
+		for _, cmd := range job.Commands {
+			m[cmd.Role]++
+		}
+		return m
+	}
+	if diff := cmp.Diff(roles(complete), roles(actual)); diff != "" {
+		t.Errorf("commands mismatch (-complete +actual):\n%s", diff)
+	}
+
+	// A job that is done replaces everything.
+	done := parseTestfile(t, "Done.xml")
+	done.Reprocessed = true
+	if err := db.Insert(ctx, done); err != nil {
+		t.Fatal(err)
+	}
+	actual, err = db.Job(ctx, jobID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(actual.Commands) != 0 || actual.StartConfigureTime != nil {
+		t.Errorf("job was not replaced: %+v", actual)
+	}
+}
+
+func TestPrevious(t *testing.T) {
+	db := openTestDB(t)
+	ctx := context.Background()
+
+	day := func(d int) *time.Time {
+		t := time.Date(2025, 2, d, 3, 0, 0, 0, time.UTC)
+		return &t
+	}
+	host := &model.Host{Site: "NUC"}
+	for _, job := range []*model.Job{
+		{JobID: "a", Project: "Example", BuildName: "Linux", Host: host, StartBuildTime: day(1), Done: true},
+		{JobID: "b", Project: "Example", BuildName: "Linux", Host: host, StartBuildTime: day(2), Done: true},
+		{JobID: "c", Project: "Example", BuildName: "Windows", Host: host, StartBuildTime: day(3), Done: true},
+		{JobID: "e", Project: "Example", BuildName: "Linux", Host: host, StartBuildTime: day(3)},
+		{JobID: "d", Project: "Example", BuildName: "Linux", Host: host, StartBuildTime: day(4), Done: true},
+	} {
+		if err := db.Insert(ctx, job); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	job, err := db.Job(ctx, "d")
+	if err != nil {
+		t.Fatal(err)
+	}
+	prev, err := db.Previous(ctx, job)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if prev.JobID != "b" {
+		t.Errorf("expected b, got %s", prev.JobID)
+	}
+
+	job, _ = db.Job(ctx, "a")
+	if _, err := db.Previous(ctx, job); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+}
@@ -0,0 +1,172 @@
+// SPDX-FileCopyrightText: 2025 Daniel Pfeifer <daniel@pfeifer-mail.de>
+// SPDX-License-Identifier: ISC
+
+package submission
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Submission is a raw upload as received from CTest. It is stored as two
+// files in a directory: <id>.body holds the request body and <id>.json the
+// metadata. The metadata is written last, so a submission without metadata
+// is incomplete and ignored.
+type Submission struct {
+	ID       string     `json:"id"`
+	Query    url.Values `json:"query"`
+	Received time.Time  `json:"received"`
+	Attempts int        `json:"attempts,omitempty"`
+	LastErr  string     `json:"last_error,omitempty"`
+
+	dir string
+}
+
+// Create stores a new submission in dir.
+func Create(dir string, query url.Values, body io.Reader) (*Submission, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+
+	s := &Submission{
+		ID:       newID(),
+		Query:    query,
+		Received: time.Now().UTC(),
+		dir:      dir,
+	}
+
+	if err := writeFile(s.BodyPath(), func(w io.Writer) error {
+		_, err := io.Copy(w, body)
+		return err
+	}); err != nil {
+		return nil, err
+	}
+
+	if err := s.Save(); err != nil {
+		os.Remove(s.BodyPath())
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// Load reads the submission with the given ID from dir.
+func Load(dir, id string) (*Submission, error) {
+	data, err := os.ReadFile(filepath.Join(dir, id+".json"))
+	if err != nil {
+		return nil, err
+	}
+
+	s := &Submission{dir: dir}
+	if err := json.Unmarshal(data, s); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// List returns all complete submissions in dir, oldest first.
+func List(dir string) ([]*Submission, error) {
+	names, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+
+	var subs []*Submission
+	for _, name := range names {
+		s, err := Load(dir, strings.TrimSuffix(filepath.Base(name), ".json"))
+		if err != nil {
+			return nil, err
+		}
+		subs = append(subs, s)
+	}
+
+	sort.Slice(subs, func(i, j int) bool {
+		return subs[i].Received.Before(subs[j].Received)
+	})
+	return subs, nil
+}
+
+func (s *Submission) BodyPath() string {
+	return filepath.Join(s.dir, s.ID+".body")
+}
+
+func (s *Submission) metaPath() string {
+	return filepath.Join(s.dir, s.ID+".json")
+}
+
+// Open opens the body for reading.
+func (s *Submission) Open() (*os.File, error) {
+	return os.Open(s.BodyPath())
+}
+
+// Save writes the metadata.
+func (s *Submission) Save() error {
+	return writeFile(s.metaPath(), func(w io.Writer) error {
+		return json.NewEncoder(w).Encode(s)
+	})
+}
+
+// MoveTo moves the submission to another directory.
+func (s *Submission) MoveTo(dir string) error {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+
+	body := filepath.Join(dir, s.ID+".body")
+	if err := os.Rename(s.BodyPath(), body); err != nil {
+		return err
+	}
+
+	meta := filepath.Join(dir, s.ID+".json")
+	if err := os.Rename(s.metaPath(), meta); err != nil {
+		return err
+	}
+
+	s.dir = dir
+	return nil
+}
+
+// Remove deletes the submission.
+func (s *Submission) Remove() error {
+	return errors.Join(os.Remove(s.metaPath()), os.Remove(s.BodyPath()))
+}
+
+// IDs sort by time of creation.
+func newID() string {
+	var suffix [4]byte
+	rand.Read(suffix[:])
+	return time.Now().UTC().Format("20060102T150405.000000000") + "-" + hex.EncodeToString(suffix[:])
+}
+
+// writeFile writes to a temporary file first, so readers never see a
+// partially written file.
+func writeFile(name string, write func(io.Writer) error) error {
+	tmp, err := os.CreateTemp(filepath.Dir(name), ".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if err := write(tmp); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), name)
+}
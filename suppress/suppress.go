@@ -0,0 +1,148 @@
+// SPDX-FileCopyrightText: 2025 Daniel Pfeifer <daniel@pfeifer-mail.de>
+// SPDX-License-Identifier: ISC
+
+// Package suppress marks known diagnostics, so new ones stand out. Suppressed
+// diagnostics are kept for auditing.
+package suppress
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/chorse-dev/cdash-proxy/fingerprint"
+	"github.com/chorse-dev/cdash-proxy/glob"
+	"github.com/chorse-dev/cdash-proxy/model"
+)
+
+// BaselineReason is the reason of diagnostics that are in the baseline.
+const BaselineReason = "baseline"
+
+// Rule suppresses the diagnostics that match all of its non-empty fields.
+// File, Option and Target are globs, Message is a regular expression and Role
+// must match exactly.
+type Rule struct {
+	File    string `json:"file,omitempty"`
+	Option  string `json:"option,omitempty"`
+	Message string `json:"message,omitempty"`
+	Target  string `json:"target,omitempty"`
+	Role    string `json:"role,omitempty"`
+
+	// Reason is recorded in the suppressed diagnostics.
+	Reason string `json:"reason,omitempty"`
+}
+
+type rule struct {
+	file, option, message, target *regexp.Regexp
+	role                          string
+	reason                        string
+}
+
+// Suppressor applies rules and a baseline to jobs.
+type Suppressor struct {
+	rules    []rule
+	baseline map[string]bool
+}
+
+// New compiles the rules. The baseline holds the fingerprints of accepted
+// diagnostics.
+func New(rules []Rule, baseline map[string]bool) (*Suppressor, error) {
+	s := &Suppressor{baseline: baseline}
+
+	var errs []error
+	for i, r := range rules {
+		compile := func(field, pattern string, fn func(string) (*regexp.Regexp, error)) *regexp.Regexp {
+			if pattern == "" {
+				return nil
+			}
+			re, err := fn(pattern)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("suppress[%d].%s: %w", i, field, err))
+			}
+			return re
+		}
+
+		reason := r.Reason
+		if reason == "" {
+			reason = fmt.Sprintf("suppress[%d]", i)
+		}
+		s.rules = append(s.rules, rule{
+			file:    compile("file", r.File, glob.Compile),
+			option:  compile("option", r.Option, glob.Compile),
+			message: compile("message", r.Message, regexp.Compile),
+			target:  compile("target", r.Target, glob.Compile),
+			role:    r.Role,
+			reason:  reason,
+		})
+	}
+	return s, errors.Join(errs...)
+}
+
+// LoadBaseline reads fingerprints from a file, one per line. Anything after
+// the fingerprint, as well as lines starting with "#", is ignored.
+func LoadBaseline(path string) (map[string]bool, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	baseline := map[string]bool{}
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+		baseline[fields[0]] = true
+	}
+	return baseline, scanner.Err()
+}
+
+// Apply marks the diagnostics of the job that match a rule or the baseline.
+func (s *Suppressor) Apply(job *model.Job) {
+	if s == nil {
+		return
+	}
+
+	for i := range job.Commands {
+		cmd := &job.Commands[i]
+		for j := range cmd.Diagnostics {
+			diag := &cmd.Diagnostics[j]
+			if reason := s.reason(cmd, diag); reason != "" {
+				diag.Suppressed = true
+				diag.SuppressedBy = reason
+			}
+		}
+	}
+}
+
+func (s *Suppressor) reason(cmd *model.Command, diag *model.Diagnostic) string {
+	for _, r := range s.rules {
+		if r.matches(cmd, diag) {
+			return r.reason
+		}
+	}
+	fp := diag.Fingerprint
+	if fp == "" {
+		fp = fingerprint.Diagnostic(diag, "")
+	}
+	if s.baseline[fp] {
+		return BaselineReason
+	}
+	return ""
+}
+
+func (r *rule) matches(cmd *model.Command, diag *model.Diagnostic) bool {
+	match := func(re *regexp.Regexp, s string) bool {
+		return re == nil || re.MatchString(s)
+	}
+	return match(r.file, diag.FilePath) &&
+		match(r.option, diag.Option) &&
+		match(r.message, diag.Message) &&
+		match(r.target, cmd.Target) &&
+		(r.role == "" || r.role == cmd.Role)
+}
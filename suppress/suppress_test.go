@@ -0,0 +1,69 @@
+// SPDX-FileCopyrightText: 2025 Daniel Pfeifer <daniel@pfeifer-mail.de>
+// SPDX-License-Identifier: ISC
+
+package suppress
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/chorse-dev/cdash-proxy/fingerprint"
+	"github.com/chorse-dev/cdash-proxy/model"
+)
+
+func TestApply(t *testing.T) {
+	accepted := model.Diagnostic{FilePath: "src/main.c", Line: 3, Type: "Warning", Message: "unused variable 'x'"}
+
+	path := filepath.Join(t.TempDir(), "baseline.txt")
+	os.WriteFile(path, []byte("# accepted\n"+fingerprint.Diagnostic(&accepted, "")+" src/main.c:3\n"), 0o644)
+	baseline, err := LoadBaseline(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s, err := New([]Rule{
+		{File: "third_party/**", Reason: "third party"},
+		{Option: "-Wdeprecated*", Target: "legacy"},
+		{Message: "^ignoring return value", Role: "compile"},
+	}, baseline)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	accepted.Line = 7
+	job := &model.Job{Commands: []model.Command{
+		{Role: "compile", Target: "app", Diagnostics: []model.Diagnostic{
+			{FilePath: "third_party/zlib/inflate.c", Type: "Warning"},
+			{FilePath: "src/app.c", Type: "Warning", Option: "-Wdeprecated-declarations"},
+			{FilePath: "src/app.c", Type: "Warning", Message: "ignoring return value of 'read'"},
+			accepted,
+		}},
+		{Role: "compile", Target: "legacy", Diagnostics: []model.Diagnostic{
+			{FilePath: "src/legacy.c", Type: "Warning", Option: "-Wdeprecated-declarations"},
+		}},
+		{Role: "test", Diagnostics: []model.Diagnostic{
+			{FilePath: "src/app.c", Type: "Error", Message: "ignoring return value of 'read'"},
+		}},
+	}}
+	s.Apply(job)
+
+	want := [][]string{
+		{"third party", "", "suppress[2]", "baseline"},
+		{"suppress[1]"},
+		{""},
+	}
+	for i, cmd := range job.Commands {
+		for j, diag := range cmd.Diagnostics {
+			if diag.SuppressedBy != want[i][j] || diag.Suppressed != (want[i][j] != "") {
+				t.Errorf("commands[%d].diagnostics[%d]: suppressed by %q, want %q", i, j, diag.SuppressedBy, want[i][j])
+			}
+		}
+	}
+}
+
+func TestNewError(t *testing.T) {
+	if _, err := New([]Rule{{Message: "("}}, nil); err == nil {
+		t.Error("expected error")
+	}
+}
@@ -0,0 +1,237 @@
+// SPDX-FileCopyrightText: 2025 Daniel Pfeifer <daniel@pfeifer-mail.de>
+// SPDX-License-Identifier: ISC
+
+// Package trace exports the commands of a job in the Chrome trace event
+// format, which trace viewers like Perfetto open as timeline.
+package trace
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/chorse-dev/cdash-proxy/model"
+)
+
+type Trace struct {
+	TraceEvents     []Event `json:"traceEvents"`
+	DisplayTimeUnit string  `json:"displayTimeUnit"`
+}
+
+// Event is a trace event. Timestamps and durations are in microseconds.
+type Event struct {
+	Name  string         `json:"name"`
+	Cat   string         `json:"cat,omitempty"`
+	Ph    string         `json:"ph"`
+	Ts    int64          `json:"ts"`
+	Dur   int64          `json:"dur,omitempty"`
+	Pid   int            `json:"pid"`
+	Tid   int            `json:"tid"`
+	Cname string         `json:"cname,omitempty"`
+	Args  map[string]any `json:"args,omitempty"`
+}
+
+// Processes of the trace, one per step of the job.
+const (
+	updatePID = iota + 1
+	configurePID
+	buildPID
+	testPID
+)
+
+var processNames = map[int]string{
+	updatePID:    "Update",
+	configurePID: "Configure",
+	buildPID:     "Build",
+	testPID:      "Test",
+}
+
+// colors are reserved color names of the trace viewer by role.
+var colors = map[string]string{
+	"update":    "rail_load",
+	"configure": "rail_load",
+	"generate":  "rail_load",
+	"compile":   "thread_state_running",
+	"link":      "thread_state_iowait",
+	"custom":    "thread_state_runnable",
+	"test":      "rail_animation",
+}
+
+type span struct {
+	cmd       *model.Command
+	start     time.Time
+	duration  time.Duration
+	estimated bool
+}
+
+// Export converts the commands of a job into complete events. Commands that
+// overlap in time are placed on separate threads, the parallel slots of the
+// build. Tests have no start time; they are placed one after another from
+// the start of the test step and marked as estimated. Other commands without
+// start time are left out.
+func Export(job *model.Job) *Trace {
+	spans := map[int][]span{}
+	var testStart time.Time
+	if job.StartTestTime != nil {
+		testStart = *job.StartTestTime
+	}
+
+	for i := range job.Commands {
+		cmd := &job.Commands[i]
+		s := span{cmd: cmd, duration: time.Duration(max(cmd.Duration, 0)) * time.Millisecond}
+		switch {
+		case cmd.StartTime != nil:
+			s.start = *cmd.StartTime
+		case cmd.Role == "test" && !testStart.IsZero():
+			s.start = testStart
+			s.estimated = true
+			testStart = testStart.Add(s.duration)
+		default:
+			continue
+		}
+		pid := process(cmd.Role)
+		spans[pid] = append(spans[pid], s)
+	}
+
+	var origin time.Time
+	for _, ss := range spans {
+		for _, s := range ss {
+			if origin.IsZero() || s.start.Before(origin) {
+				origin = s.start
+			}
+		}
+	}
+
+	trace := &Trace{TraceEvents: []Event{}, DisplayTimeUnit: "ms"}
+	for _, pid := range []int{updatePID, configurePID, buildPID, testPID} {
+		ss := spans[pid]
+		if len(ss) == 0 {
+			continue
+		}
+		trace.TraceEvents = append(trace.TraceEvents, Event{
+			Name: "process_name",
+			Ph:   "M",
+			Pid:  pid,
+			Args: map[string]any{"name": processNames[pid]},
+		})
+
+		slices.SortStableFunc(ss, func(a, b span) int {
+			return a.start.Compare(b.start)
+		})
+		tids := assignSlots(ss)
+		if slices.Contains(tids, 0) {
+			trace.TraceEvents = append(trace.TraceEvents, Event{
+				Name: "thread_name",
+				Ph:   "M",
+				Pid:  pid,
+				Args: map[string]any{"name": "build tool"},
+			})
+		}
+		for tid := 1; tid <= slices.Max(tids); tid++ {
+			trace.TraceEvents = append(trace.TraceEvents, Event{
+				Name: "thread_name",
+				Ph:   "M",
+				Pid:  pid,
+				Tid:  tid,
+				Args: map[string]any{"name": fmt.Sprintf("slot %d", tid)},
+			})
+		}
+		for i, tid := range tids {
+			trace.TraceEvents = append(trace.TraceEvents, event(ss[i], pid, tid, origin))
+		}
+	}
+	return trace
+}
+
+// Write writes the trace of a job as JSON.
+func Write(w io.Writer, job *model.Job) error {
+	return json.NewEncoder(w).Encode(Export(job))
+}
+
+func process(role string) int {
+	switch role {
+	case "update":
+		return updatePID
+	case "configure", "generate":
+		return configurePID
+	case "test":
+		return testPID
+	}
+	return buildPID
+}
+
+// assignSlots places each span, sorted by start, on the first slot that is
+// free at its start. Slots are numbered from 1. The invocations of the build
+// tool, like "cmake --build", enclose the other commands and are placed on
+// slot 0.
+func assignSlots(spans []span) []int {
+	var ends []time.Time
+	tids := make([]int, len(spans))
+	for i, s := range spans {
+		if strings.HasPrefix(s.cmd.Role, "cmake") {
+			continue
+		}
+		slot := slices.IndexFunc(ends, func(end time.Time) bool {
+			return !end.After(s.start)
+		})
+		if slot == -1 {
+			slot = len(ends)
+			ends = append(ends, time.Time{})
+		}
+		ends[slot] = s.start.Add(s.duration)
+		tids[i] = slot + 1
+	}
+	return tids
+}
+
+func event(s span, pid, tid int, origin time.Time) Event {
+	cmd := s.cmd
+	args := map[string]any{}
+	for key, value := range map[string]string{
+		"target":       cmd.Target,
+		"source":       cmd.Source,
+		"command_line": cmd.CommandLine,
+		"test_status":  cmd.TestStatus,
+	} {
+		if value != "" {
+			args[key] = value
+		}
+	}
+	if cmd.Result != 0 {
+		args["result"] = cmd.Result
+	}
+	if s.estimated {
+		args["estimated"] = true
+	}
+
+	return Event{
+		Name:  name(cmd),
+		Cat:   cmd.Role,
+		Ph:    "X",
+		Ts:    s.start.Sub(origin).Microseconds(),
+		Dur:   s.duration.Microseconds(),
+		Pid:   pid,
+		Tid:   tid,
+		Cname: colors[cmd.Role],
+		Args:  args,
+	}
+}
+
+func name(cmd *model.Command) string {
+	switch {
+	case cmd.TestName != "":
+		return cmd.TestName
+	case cmd.Source != "":
+		return cmd.Source
+	case cmd.Role == "link" && cmd.Target != "":
+		return cmd.Target
+	case len(cmd.Outputs) != 0:
+		return cmd.Outputs[0]
+	case cmd.Target != "":
+		return cmd.Target
+	}
+	return cmd.Role
+}
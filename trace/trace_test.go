@@ -0,0 +1,92 @@
+// SPDX-FileCopyrightText: 2025 Daniel Pfeifer <daniel@pfeifer-mail.de>
+// SPDX-License-Identifier: ISC
+
+package trace
+
+import (
+	"testing"
+	"time"
+
+	"github.com/chorse-dev/cdash-proxy/model"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestExport(t *testing.T) {
+	t0 := time.Date(2025, 5, 19, 20, 0, 0, 0, time.UTC)
+	at := func(ms int) *time.Time {
+		t := t0.Add(time.Duration(ms) * time.Millisecond)
+		return &t
+	}
+
+	job := &model.Job{
+		StartTestTime: at(1000),
+		Commands: []model.Command{
+			{Role: "configure", StartTime: at(0), Duration: 100},
+			{Role: "cmakeBuild", StartTime: at(100), Duration: 600},
+			{Role: "compile", Source: "a.c", StartTime: at(100), Duration: 300},
+			{Role: "compile", Source: "b.c", StartTime: at(150), Duration: 100, Result: 1},
+			{Role: "compile", Source: "c.c", StartTime: at(250), Duration: 100},
+			{Role: "link", Target: "app", StartTime: at(400), Duration: 200},
+			{Role: "compile", Source: "failed.c"},
+			{Role: "test", TestName: "one", TestStatus: "passed", Duration: 50},
+			{Role: "test", TestName: "two", TestStatus: "failed", Duration: 20},
+		},
+	}
+
+	meta := func(name string, pid, tid int, value string) Event {
+		return Event{Name: name, Ph: "M", Pid: pid, Tid: tid, Args: map[string]any{"name": value}}
+	}
+	want := &Trace{
+		DisplayTimeUnit: "ms",
+		TraceEvents: []Event{
+			meta("process_name", 2, 0, "Configure"),
+			meta("thread_name", 2, 1, "slot 1"),
+			{Name: "configure", Cat: "configure", Ph: "X", Ts: 0, Dur: 100000, Pid: 2, Tid: 1,
+				Cname: "rail_load", Args: map[string]any{}},
+
+			meta("process_name", 3, 0, "Build"),
+			meta("thread_name", 3, 0, "build tool"),
+			meta("thread_name", 3, 1, "slot 1"),
+			meta("thread_name", 3, 2, "slot 2"),
+			{Name: "cmakeBuild", Cat: "cmakeBuild", Ph: "X", Ts: 100000, Dur: 600000, Pid: 3, Tid: 0,
+				Args: map[string]any{}},
+			{Name: "a.c", Cat: "compile", Ph: "X", Ts: 100000, Dur: 300000, Pid: 3, Tid: 1,
+				Cname: "thread_state_running", Args: map[string]any{"source": "a.c"}},
+			{Name: "b.c", Cat: "compile", Ph: "X", Ts: 150000, Dur: 100000, Pid: 3, Tid: 2,
+				Cname: "thread_state_running", Args: map[string]any{"source": "b.c", "result": 1}},
+			{Name: "c.c", Cat: "compile", Ph: "X", Ts: 250000, Dur: 100000, Pid: 3, Tid: 2,
+				Cname: "thread_state_running", Args: map[string]any{"source": "c.c"}},
+			{Name: "app", Cat: "link", Ph: "X", Ts: 400000, Dur: 200000, Pid: 3, Tid: 1,
+				Cname: "thread_state_iowait", Args: map[string]any{"target": "app"}},
+
+			meta("process_name", 4, 0, "Test"),
+			meta("thread_name", 4, 1, "slot 1"),
+			{Name: "one", Cat: "test", Ph: "X", Ts: 1000000, Dur: 50000, Pid: 4, Tid: 1,
+				Cname: "rail_animation", Args: map[string]any{"test_status": "passed", "estimated": true}},
+			{Name: "two", Cat: "test", Ph: "X", Ts: 1050000, Dur: 20000, Pid: 4, Tid: 1,
+				Cname: "rail_animation", Args: map[string]any{"test_status": "failed", "estimated": true}},
+		},
+	}
+
+	if diff := cmp.Diff(want, Export(job)); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestExportUpdate(t *testing.T) {
+	start := time.Date(2025, 5, 19, 20, 0, 0, 0, time.UTC)
+	job := &model.Job{Commands: []model.Command{
+		{Role: "update", CommandLine: "git fetch", StartTime: &start, Duration: 50},
+	}}
+
+	events := Export(job).TraceEvents
+	if n := len(events); n != 3 {
+		t.Fatalf("expected 3 events, got %d", n)
+	}
+	if name := events[0].Args["name"]; name != "Update" {
+		t.Errorf("update is in process %v", name)
+	}
+	if pid := events[2].Pid; pid != updatePID {
+		t.Errorf("update has process %d", pid)
+	}
+}
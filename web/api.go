@@ -0,0 +1,211 @@
+// SPDX-FileCopyrightText: 2025 Daniel Pfeifer <daniel@pfeifer-mail.de>
+// SPDX-License-Identifier: ISC
+
+package web
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/chorse-dev/cdash-proxy/buildstats"
+	"github.com/chorse-dev/cdash-proxy/compare"
+	"github.com/chorse-dev/cdash-proxy/coverage"
+	"github.com/chorse-dev/cdash-proxy/junit"
+	"github.com/chorse-dev/cdash-proxy/model"
+	"github.com/chorse-dev/cdash-proxy/otlp"
+	"github.com/chorse-dev/cdash-proxy/sarif"
+	"github.com/chorse-dev/cdash-proxy/storage"
+	"github.com/chorse-dev/cdash-proxy/trace"
+)
+
+const defaultLimit = 100
+
+// API serves the stored jobs as JSON in the shapes of the model package.
+func API(db *storage.DB) http.Handler {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("GET /api/v1/jobs", func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		filter := storage.JobFilter{
+			Project:   query.Get("project"),
+			BuildName: query.Get("build_name"),
+		}
+
+		if since := query.Get("since"); since != "" {
+			t, err := parseTime(since)
+			if err != nil {
+				sendError(w, http.StatusBadRequest, err)
+				return
+			}
+			filter.Since = t
+		}
+
+		limit, err := parseLimit(query)
+		if err != nil {
+			sendError(w, http.StatusBadRequest, err)
+			return
+		}
+		filter.Limit = limit
+
+		jobs, err := db.Jobs(r.Context(), filter)
+		sendJSON(w, jobs, err)
+	})
+
+	mux.HandleFunc("GET /api/v1/jobs/{job_id}", func(w http.ResponseWriter, r *http.Request) {
+		job, err := db.Job(r.Context(), r.PathValue("job_id"))
+		sendJSON(w, job, err)
+	})
+
+	mux.HandleFunc("GET /api/v1/jobs/{job_id}/commands", func(w http.ResponseWriter, r *http.Request) {
+		jobID := r.PathValue("job_id")
+		if exists, err := db.JobExists(r.Context(), jobID); !exists {
+			if err == nil {
+				err = storage.ErrNotFound
+			}
+			sendJSON(w, nil, err)
+			return
+		}
+
+		query := r.URL.Query()
+		cmds, err := db.Commands(r.Context(), jobID, storage.CommandFilter{
+			Role:       query.Get("role"),
+			TestStatus: query.Get("status"),
+		})
+		sendJSON(w, cmds, err)
+	})
+
+	mux.HandleFunc("GET /api/v1/jobs/{job_id}/compare", func(w http.ResponseWriter, r *http.Request) {
+		job, err := db.Job(r.Context(), r.PathValue("job_id"))
+		if err != nil {
+			sendJSON(w, nil, err)
+			return
+		}
+
+		var baseline *model.Job
+		if id := r.URL.Query().Get("baseline"); id != "" {
+			baseline, err = db.Job(r.Context(), id)
+		} else {
+			baseline, err = db.Previous(r.Context(), job)
+		}
+		if errors.Is(err, storage.ErrNotFound) {
+			sendError(w, http.StatusNotFound, errors.New("baseline job not found"))
+			return
+		}
+		if err != nil {
+			sendJSON(w, nil, err)
+			return
+		}
+
+		sendJSON(w, compare.Compare(job, baseline), nil)
+	})
+
+	mux.HandleFunc("GET /api/v1/jobs/{job_id}/sarif", exportJob(db, "application/sarif+json", sarif.Write))
+	mux.HandleFunc("GET /api/v1/jobs/{job_id}/junit", exportJob(db, "application/xml", junit.Write))
+	mux.HandleFunc("GET /api/v1/jobs/{job_id}/cobertura", exportJob(db, "application/xml", coverage.WriteCobertura))
+	mux.HandleFunc("GET /api/v1/jobs/{job_id}/lcov", exportJob(db, "text/plain; charset=utf-8", coverage.WriteLCOV))
+	mux.HandleFunc("GET /api/v1/jobs/{job_id}/trace", exportJob(db, "application/json", trace.Write))
+	mux.HandleFunc("GET /api/v1/jobs/{job_id}/otlp", exportJob(db, "application/json", otlp.Write))
+	mux.HandleFunc("GET /api/v1/jobs/{job_id}/buildstats", exportJob(db, "application/json", buildstats.Write))
+
+	mux.HandleFunc("GET /api/v1/diagnostics", func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		filter := storage.DiagnosticFilter{
+			JobID:    query.Get("job_id"),
+			FilePath: query.Get("file_path"),
+			Type:     query.Get("type"),
+
+			Fingerprint: query.Get("fingerprint"),
+		}
+
+		if suppressed := query.Get("suppressed"); suppressed != "" {
+			b, err := strconv.ParseBool(suppressed)
+			if err != nil {
+				sendError(w, http.StatusBadRequest, fmt.Errorf("invalid suppressed %q", suppressed))
+				return
+			}
+			filter.Suppressed = &b
+		}
+
+		limit, err := parseLimit(query)
+		if err != nil {
+			sendError(w, http.StatusBadRequest, err)
+			return
+		}
+		filter.Limit = limit
+
+		diags, err := db.Diagnostics(r.Context(), filter)
+		sendJSON(w, diags, err)
+	})
+
+	return mux
+}
+
+// exportJob serves a stored job in another format.
+func exportJob(db *storage.DB, contentType string, write func(io.Writer, *model.Job) error) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		job, err := db.Job(r.Context(), r.PathValue("job_id"))
+		if err != nil {
+			sendJSON(w, nil, err)
+			return
+		}
+
+		w.Header().Set("Content-Type", contentType)
+		if err := write(w, job); err != nil {
+			log.Printf("%s: %v", r.URL.Path, err)
+		}
+	}
+}
+
+// parseLimit returns the limit of the number of results, which is
+// defaultLimit unless given.
+func parseLimit(query url.Values) (int, error) {
+	limit := query.Get("limit")
+	if limit == "" {
+		return defaultLimit, nil
+	}
+	n, err := strconv.Atoi(limit)
+	if err != nil || n <= 0 {
+		return 0, fmt.Errorf("invalid limit %q", limit)
+	}
+	return n, nil
+}
+
+// parseTime accepts RFC 3339 timestamps as well as plain dates.
+func parseTime(s string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	if t, err := time.Parse(time.DateOnly, s); err == nil {
+		return t, nil
+	}
+	return time.Time{}, fmt.Errorf("invalid time %q", s)
+}
+
+func sendJSON(w http.ResponseWriter, v any, err error) {
+	if errors.Is(err, storage.ErrNotFound) {
+		sendError(w, http.StatusNotFound, err)
+		return
+	}
+	if err != nil {
+		sendError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
+
+func sendError(w http.ResponseWriter, code int, err error) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(struct {
+		Error string `json:"error"`
+	}{err.Error()})
+}
@@ -0,0 +1,182 @@
+// SPDX-FileCopyrightText: 2025 Daniel Pfeifer <daniel@pfeifer-mail.de>
+// SPDX-License-Identifier: ISC
+
+package web
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/chorse-dev/cdash-proxy/model"
+	"github.com/chorse-dev/cdash-proxy/sarif"
+	"github.com/chorse-dev/cdash-proxy/storage"
+	"github.com/google/go-cmp/cmp"
+)
+
+func newTestAPI(t *testing.T) http.Handler {
+	db, err := storage.Open(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	serve := Serve(db.Store)
+	for _, name := range []string{"Build.xml", "Test.xml"} {
+		file, err := os.Open("../ctestxml/testdata/" + name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer file.Close()
+		w := httptest.NewRecorder()
+		serve(w, httptest.NewRequest(http.MethodPut, "/submit?project=Example&FileName="+name, file))
+		if w.Code != http.StatusOK {
+			t.Fatalf("%s: %s", name, w.Body.String())
+		}
+	}
+
+	return API(db)
+}
+
+func get(t *testing.T, h http.Handler, url string, code int, v any) {
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, url, nil))
+	if w.Code != code {
+		t.Fatalf("GET %s: expected status %d, got %d: %s", url, code, w.Code, w.Body.String())
+	}
+	if v != nil {
+		if err := json.NewDecoder(w.Body).Decode(v); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestAPIJobs(t *testing.T) {
+	api := newTestAPI(t)
+
+	var jobs []model.Job
+	get(t, api, "/api/v1/jobs?project=Example", http.StatusOK, &jobs)
+	if len(jobs) == 0 {
+		t.Fatal("expected jobs")
+	}
+
+	get(t, api, "/api/v1/jobs?project=Other", http.StatusOK, &jobs)
+	if len(jobs) != 0 {
+		t.Errorf("expected no jobs, got %d", len(jobs))
+	}
+
+	get(t, api, "/api/v1/jobs?since=yesterday", http.StatusBadRequest, nil)
+	get(t, api, "/api/v1/jobs/unknown", http.StatusNotFound, nil)
+}
+
+func TestAPICommands(t *testing.T) {
+	api := newTestAPI(t)
+	const jobID = "4e5a4b59fc4badd8ec47227aa4514ba1"
+
+	var cmds []model.Command
+	get(t, api, "/api/v1/jobs/"+jobID+"/commands?role=test&status=failed", http.StatusOK, &cmds)
+	if len(cmds) != 1 || cmds[0].TestName != "Failures.FPE" {
+		t.Errorf("expected the failed test Failures.FPE, got %+v", cmds)
+	}
+
+	get(t, api, "/api/v1/jobs/"+jobID+"/commands?role=test", http.StatusOK, &cmds)
+	status := map[string]int{}
+	for _, cmd := range cmds {
+		status[cmd.TestStatus]++
+	}
+	if diff := cmp.Diff(map[string]int{"passed": 7, "failed": 1, "notrun": 1}, status); diff != "" {
+		t.Errorf("test status mismatch (-want +got):\n%s", diff)
+	}
+
+	var diags []model.Diagnostic
+	get(t, api, "/api/v1/diagnostics?type=Warning", http.StatusOK, &diags)
+	if len(diags) != 3 {
+		t.Errorf("expected 3 warnings, got %d", len(diags))
+	}
+	for _, d := range diags {
+		if d.Type != "Warning" || d.FilePath != "Failures/fpe.c" {
+			t.Errorf("unexpected diagnostic %s in %s", d.Type, d.FilePath)
+		}
+	}
+
+	get(t, api, "/api/v1/diagnostics?file_path=Failures/fpe.c", http.StatusOK, &diags)
+	if len(diags) != 3 {
+		t.Errorf("expected 3 diagnostics in Failures/fpe.c, got %d", len(diags))
+	}
+	get(t, api, "/api/v1/diagnostics?file_path=unknown.c", http.StatusOK, &diags)
+	if len(diags) != 0 {
+		t.Errorf("expected no diagnostics in unknown.c, got %d", len(diags))
+	}
+
+	// The diagnostics are attached to the commands that reported them.
+	get(t, api, "/api/v1/jobs/"+jobID+"/commands", http.StatusOK, &cmds)
+	attached := 0
+	for _, cmd := range cmds {
+		attached += len(cmd.Diagnostics)
+	}
+	get(t, api, "/api/v1/diagnostics?job_id="+jobID, http.StatusOK, &diags)
+	if attached == 0 || attached != len(diags) {
+		t.Errorf("expected %d diagnostics of commands, got %d", len(diags), attached)
+	}
+	get(t, api, "/api/v1/jobs/unknown/commands", http.StatusNotFound, nil)
+
+	get(t, api, "/api/v1/diagnostics?limit=2", http.StatusOK, &diags)
+	if len(diags) != 2 {
+		t.Errorf("expected 2 diagnostics, got %d", len(diags))
+	}
+	get(t, api, "/api/v1/diagnostics?limit=none", http.StatusBadRequest, nil)
+}
+
+func TestAPISARIF(t *testing.T) {
+	api := newTestAPI(t)
+
+	var jobs []model.Job
+	get(t, api, "/api/v1/jobs", http.StatusOK, &jobs)
+
+	results := 0
+	for _, job := range jobs {
+		var log sarif.Log
+		get(t, api, "/api/v1/jobs/"+job.JobID+"/sarif", http.StatusOK, &log)
+		if log.Version != sarif.Version {
+			t.Errorf("unexpected version %q", log.Version)
+		}
+		for _, run := range log.Runs {
+			results += len(run.Results)
+		}
+	}
+	if results == 0 {
+		t.Error("expected results")
+	}
+}
+
+func TestAPIExport(t *testing.T) {
+	api := newTestAPI(t)
+
+	var jobs []model.Job
+	get(t, api, "/api/v1/jobs", http.StatusOK, &jobs)
+
+	for _, format := range []struct {
+		name        string
+		contentType string
+	}{
+		{"sarif", "application/sarif+json"},
+		{"junit", "application/xml"},
+		{"cobertura", "application/xml"},
+		{"lcov", "text/plain; charset=utf-8"},
+		{"trace", "application/json"},
+		{"otlp", "application/json"},
+		{"buildstats", "application/json"},
+	} {
+		for _, job := range jobs {
+			w := httptest.NewRecorder()
+			api.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/jobs/"+job.JobID+"/"+format.name, nil))
+			if w.Code != http.StatusOK || w.Header().Get("Content-Type") != format.contentType {
+				t.Errorf("%s: unexpected response %d %s", format.name, w.Code, w.Header().Get("Content-Type"))
+			}
+		}
+		get(t, api, "/api/v1/jobs/unknown/"+format.name, http.StatusNotFound, nil)
+	}
+}
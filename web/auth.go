@@ -0,0 +1,125 @@
+// SPDX-FileCopyrightText: 2025 Daniel Pfeifer <daniel@pfeifer-mail.de>
+// SPDX-License-Identifier: ISC
+
+package web
+
+import (
+	"encoding/xml"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"os"
+
+	"github.com/chorse-dev/cdash-proxy/auth"
+	"github.com/chorse-dev/cdash-proxy/ctestxml"
+)
+
+var (
+	errUnknownBuild = errors.New("unknown build ID")
+	errUnknownSite  = errors.New("token is restricted to sites, but the submission has no site")
+)
+
+type build struct {
+	project string
+	site    string
+}
+
+// authorize checks the bearer token of a submission. Uploads of the second
+// step of the upload protocol carry no project, it is looked up from the
+// first step by the build ID. Uploads for unannounced build IDs are rejected,
+// since they could add files to the jobs of any project. For tokens that are
+// restricted to sites, the site of a job is remembered from its first part
+// with a site, since parts like Done.xml have none.
+func (s *Server) authorize(r *http.Request) error {
+	token := auth.BearerToken(r)
+
+	if r.Method == http.MethodPost {
+		return s.Auth.Authorize(token, r.FormValue("project"), r.FormValue("site"))
+	}
+
+	query := r.URL.Query()
+	if !isCTestXML(query) {
+		if b, found := s.lookupBuild(query.Get("buildid")); found {
+			return s.Auth.Authorize(token, b.project, b.site)
+		}
+		return errUnknownBuild
+	}
+
+	project := query.Get("project")
+	if err := s.Auth.Authorize(token, project, ""); err != nil {
+		return err
+	}
+	if !s.Auth.RestrictsSites(token) {
+		return nil
+	}
+
+	// The site is only known from the XML content.
+	body, err := rewindable(r)
+	if err != nil {
+		return err
+	}
+	h, err := ctestxml.Peek(body, project)
+	if _, seekErr := body.Seek(0, io.SeekStart); seekErr != nil {
+		return seekErr
+	}
+	if err != nil {
+		return err
+	}
+	if h.Site == "" {
+		b, found := s.lookupBuild(h.JobID)
+		if !found || b.site == "" {
+			return errUnknownSite
+		}
+		h.Site = b.site
+	}
+	if err := s.Auth.Authorize(token, project, h.Site); err != nil {
+		return err
+	}
+	if _, found := s.lookupBuild(h.JobID); !found {
+		s.builds.Store(h.JobID, build{project, h.Site})
+	}
+	return nil
+}
+
+// rewindable replaces the body of r with a file, so it can be read twice.
+// The caller closes the file.
+func rewindable(r *http.Request) (io.ReadSeeker, error) {
+	if f, ok := r.Body.(*os.File); ok {
+		return f, nil
+	}
+
+	tmp, err := os.CreateTemp("", "cdash-proxy-*")
+	if err != nil {
+		return nil, err
+	}
+	os.Remove(tmp.Name())
+
+	if _, err := io.Copy(tmp, r.Body); err != nil {
+		tmp.Close()
+		return nil, err
+	}
+	if _, err := tmp.Seek(0, io.SeekStart); err != nil {
+		tmp.Close()
+		return nil, err
+	}
+
+	r.Body = tmp
+	return tmp, nil
+}
+
+func sendAuthError(w http.ResponseWriter, err error) {
+	log.Println(err)
+
+	code := http.StatusForbidden
+	if errors.Is(err, auth.ErrMissingToken) || errors.Is(err, auth.ErrInvalidToken) {
+		code = http.StatusUnauthorized
+		w.Header().Set("WWW-Authenticate", "Bearer")
+	}
+	w.WriteHeader(code)
+
+	xml.NewEncoder(w).Encode(&ctestxml.Response{
+		Status:  "ERROR",
+		Message: err.Error(),
+	})
+}
@@ -0,0 +1,151 @@
+// SPDX-FileCopyrightText: 2025 Daniel Pfeifer <daniel@pfeifer-mail.de>
+// SPDX-License-Identifier: ISC
+
+package web
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/chorse-dev/cdash-proxy/auth"
+	"github.com/chorse-dev/cdash-proxy/model"
+)
+
+func TestAuth(t *testing.T) {
+	s := &Server{
+		Handler: func(ctx context.Context, job *model.Job) error { return nil },
+		Auth: &auth.Tokens{Tokens: []auth.Token{
+			{Token: "secret", Projects: []string{"Example"}},
+			{Token: "ci", Projects: []string{"Example"}, Sites: []string{"other"}},
+		}},
+	}
+
+	tests := []struct {
+		token string
+		code  int
+		body  string
+	}{
+		{"", http.StatusUnauthorized, `<cdash><status>ERROR</status><message>authentication required</message></cdash>`},
+		{"wrong", http.StatusUnauthorized, `<cdash><status>ERROR</status><message>invalid token</message></cdash>`},
+		{"ci", http.StatusForbidden, `<cdash><status>ERROR</status><message>token is not valid for site &#34;NUC&#34;</message></cdash>`},
+		{"secret", http.StatusOK, `<cdash><status>OK</status><buildId>4e5a4b59fc4badd8ec47227aa4514ba1</buildId></cdash>`},
+	}
+
+	for _, test := range tests {
+		file, _ := os.Open("../ctestxml/testdata/Configure.xml")
+		r := httptest.NewRequest(http.MethodPut, "/submit?project=Example&FileName=Configure.xml", file)
+		if test.token != "" {
+			r.Header.Set("Authorization", "Bearer "+test.token)
+		}
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, r)
+		file.Close()
+
+		if w.Code != test.code || w.Body.String() != test.body {
+			t.Errorf("token %q: unexpected response %d %s", test.token, w.Code, w.Body.String())
+		}
+	}
+}
+
+func TestAuthBeforeForward(t *testing.T) {
+	cdash := &fakeCDash{}
+	upstream := httptest.NewServer(cdash)
+	defer upstream.Close()
+
+	s := &Server{
+		Handler:   func(ctx context.Context, job *model.Job) error { return nil },
+		Auth:      &auth.Tokens{Tokens: []auth.Token{{Token: "secret", Projects: []string{"Example"}}}},
+		Forwarder: &Forwarder{Upstream: upstream.URL + "/submit.php", Primary: true},
+	}
+
+	body := putConfigure(t, s)
+	if !strings.Contains(body, "<message>authentication required</message>") {
+		t.Errorf("expected authentication error, got %s", body)
+	}
+	if len(cdash.uploads) != 0 {
+		t.Errorf("unauthenticated upload was forwarded: %v", cdash.uploads)
+	}
+}
+
+func TestAuthUnknownBuild(t *testing.T) {
+	s := &Server{
+		Handler: func(ctx context.Context, job *model.Job) error { return nil },
+		Auth:    &auth.Tokens{Tokens: []auth.Token{{Token: "secret", Projects: []string{"Other"}}}},
+	}
+
+	r := httptest.NewRequest(http.MethodPut, "/submit.php?type=GcovTar&buildid=4e5a4b59fc4badd8ec47227aa4514ba1", strings.NewReader(""))
+	r.Header.Set("Authorization", "Bearer secret")
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, r)
+
+	if w.Code != http.StatusForbidden || !strings.Contains(w.Body.String(), "unknown build ID") {
+		t.Errorf("unexpected response %d %s", w.Code, w.Body.String())
+	}
+}
+
+func TestAuthDoneWithoutSite(t *testing.T) {
+	s := &Server{
+		Handler: func(ctx context.Context, job *model.Job) error { return nil },
+		Auth: &auth.Tokens{Tokens: []auth.Token{
+			{Token: "ci", Projects: []string{"Example"}, Sites: []string{"NUC"}},
+		}},
+	}
+
+	put := func(name string) *httptest.ResponseRecorder {
+		file, _ := os.Open("../ctestxml/testdata/" + name)
+		defer file.Close()
+		r := httptest.NewRequest(http.MethodPut, "/submit.php?project=Example&FileName="+name, file)
+		r.Header.Set("Authorization", "Bearer ci")
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, r)
+		return w
+	}
+
+	// The site of Done.xml is known from the other parts of the job only.
+	if w := put("Done.xml"); w.Code != http.StatusForbidden || !strings.Contains(w.Body.String(), "has no site") {
+		t.Errorf("unexpected response %d %s", w.Code, w.Body.String())
+	}
+	for _, name := range []string{"Configure.xml", "Done.xml"} {
+		if w := put(name); w.Code != http.StatusOK {
+			t.Errorf("%s: unexpected response %d %s", name, w.Code, w.Body.String())
+		}
+	}
+}
+
+func TestAuthBody(t *testing.T) {
+	s := &Server{
+		Handler: func(ctx context.Context, job *model.Job) error { return nil },
+		Auth: &auth.Tokens{Tokens: []auth.Token{
+			{Token: "ci", Projects: []string{"Example"}, Sites: []string{"NUC"}},
+		}},
+		BodyLimit: func(project string) int64 { return 1000 },
+	}
+
+	for size, code := range map[int]int{10: http.StatusOK, 2000: http.StatusRequestEntityTooLarge} {
+		body := `<Site Name="NUC" BuildName="b" BuildStamp="s">` + strings.Repeat(" ", size) + `</Site>`
+		r := httptest.NewRequest(http.MethodPut, "/submit.php?project=Example&FileName=Configure.xml", strings.NewReader(body))
+		r.ContentLength = -1
+		r.Header.Set("Authorization", "Bearer ci")
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, r)
+
+		if w.Code != code {
+			t.Errorf("size %d: got %d, want %d: %s", size, w.Code, code, w.Body.String())
+		}
+		if code != http.StatusOK {
+			continue
+		}
+		f, ok := r.Body.(*os.File)
+		if !ok {
+			t.Fatalf("size %d: expected the body to be a temporary file", size)
+		}
+		if _, err := f.Stat(); !errors.Is(err, os.ErrClosed) {
+			t.Errorf("size %d: temporary file was not closed", size)
+		}
+	}
+}
@@ -0,0 +1,73 @@
+// SPDX-FileCopyrightText: 2025 Daniel Pfeifer <daniel@pfeifer-mail.de>
+// SPDX-License-Identifier: ISC
+
+package web
+
+import (
+	"sync"
+	"time"
+)
+
+// buildTTL is how long the build IDs of the upload protocol are remembered.
+// It covers the longest dashboard runs.
+const buildTTL = 24 * time.Hour
+
+// expiring is a map whose entries expire after buildTTL. Expired entries are
+// removed while storing, at most once per minute. The zero value is empty.
+type expiring[V any] struct {
+	mu      sync.Mutex
+	entries map[string]expiringEntry[V]
+	swept   time.Time
+
+	// now is replaced in tests.
+	now func() time.Time
+}
+
+type expiringEntry[V any] struct {
+	value   V
+	expires time.Time
+}
+
+func (m *expiring[V]) Load(key string) (V, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	e, found := m.entries[key]
+	if !found || !m.time().Before(e.expires) {
+		var zero V
+		return zero, false
+	}
+	return e.value, true
+}
+
+func (m *expiring[V]) Store(key string, value V) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := m.time()
+	if m.entries == nil {
+		m.entries = map[string]expiringEntry[V]{}
+	}
+	if now.Sub(m.swept) > time.Minute {
+		for k, e := range m.entries {
+			if !now.Before(e.expires) {
+				delete(m.entries, k)
+			}
+		}
+		m.swept = now
+	}
+	m.entries[key] = expiringEntry[V]{value, now.Add(buildTTL)}
+}
+
+func (m *expiring[V]) Len() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return len(m.entries)
+}
+
+func (m *expiring[V]) time() time.Time {
+	if m.now != nil {
+		return m.now()
+	}
+	return time.Now()
+}
@@ -0,0 +1,29 @@
+// SPDX-FileCopyrightText: 2025 Daniel Pfeifer <daniel@pfeifer-mail.de>
+// SPDX-License-Identifier: ISC
+
+package web
+
+import (
+	"testing"
+	"time"
+)
+
+func TestExpiring(t *testing.T) {
+	now := time.Date(2025, 5, 19, 20, 0, 0, 0, time.UTC)
+	m := expiring[string]{now: func() time.Time { return now }}
+
+	m.Store("a", "Example")
+	if v, found := m.Load("a"); !found || v != "Example" {
+		t.Errorf("Load(a) = %q, %v", v, found)
+	}
+
+	now = now.Add(buildTTL)
+	if _, found := m.Load("a"); found {
+		t.Error("expected a to be expired")
+	}
+
+	m.Store("b", "Other")
+	if n := m.Len(); n != 1 {
+		t.Errorf("expected expired entries to be removed, got %d entries", n)
+	}
+}
@@ -0,0 +1,267 @@
+// SPDX-FileCopyrightText: 2025 Daniel Pfeifer <daniel@pfeifer-mail.de>
+// SPDX-License-Identifier: ISC
+
+package web
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"os"
+
+	"github.com/chorse-dev/cdash-proxy/submission"
+)
+
+// Forwarder relays every submission to an upstream CDash server in addition
+// to processing it locally.
+type Forwarder struct {
+	// Upstream is the URL of CDash's submit.php.
+	Upstream string
+
+	// Primary makes the response of the upstream server the response to the
+	// client. Otherwise, the client receives the local response. If the
+	// upstream server cannot be reached, the local response is used either
+	// way.
+	Primary bool
+
+	// FailedDir, if set, is a directory where submissions that could not be
+	// forwarded are kept.
+	FailedDir string
+
+	Client *http.Client
+
+	// CTest passes the buildid from the response to the POST request to the
+	// subsequent PUT request. Local job IDs and upstream build IDs differ, so
+	// they are mapped in both directions.
+	toLocal    expiring[string]
+	toUpstream expiring[string]
+}
+
+type upstreamResponse struct {
+	header http.Header
+	code   int
+	body   []byte
+	err    error
+}
+
+func (f *Forwarder) Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPut && r.Method != http.MethodPost {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		body, err := os.CreateTemp("", "cdash-proxy-*")
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		defer os.Remove(body.Name())
+		defer body.Close()
+
+		if _, err := io.Copy(body, r.Body); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		original := r.Clone(r.Context())
+		upstream := make(chan upstreamResponse, 1)
+		go func() {
+			upstream <- f.forward(original, body.Name())
+		}()
+
+		local := &responseBuffer{header: http.Header{}, code: http.StatusOK}
+		r.Body = io.NopCloser(io.NewSectionReader(body, 0, 1<<62))
+		f.rewriteBuildID(r, f.localID)
+		next.ServeHTTP(local, r)
+
+		up := <-upstream
+		if up.err == nil && up.code >= 300 {
+			up.err = &upstreamError{up.code, up.body}
+		}
+		if up.err != nil {
+			log.Printf("forward to %s: %v", f.Upstream, up.err)
+			f.keepFailed(original, body)
+		}
+
+		if r.Method == http.MethodPost && up.err == nil {
+			f.remember(buildID(local.body.Bytes()), buildID(up.body))
+		}
+
+		if f.Primary && up.err == nil {
+			if r.Method == http.MethodPost {
+				up.body = localFiles(up.body, local.body.Bytes())
+			}
+			copyHeader(w.Header(), up.header)
+			w.WriteHeader(up.code)
+			w.Write(up.body)
+			return
+		}
+
+		copyHeader(w.Header(), local.header)
+		w.WriteHeader(local.code)
+		w.Write(local.body.Bytes())
+	})
+}
+
+func (f *Forwarder) forward(r *http.Request, name string) upstreamResponse {
+	body, err := os.Open(name)
+	if err != nil {
+		return upstreamResponse{err: err}
+	}
+	defer body.Close()
+
+	info, err := body.Stat()
+	if err != nil {
+		return upstreamResponse{err: err}
+	}
+
+	req, err := http.NewRequestWithContext(r.Context(), r.Method, f.Upstream, body)
+	if err != nil {
+		return upstreamResponse{err: err}
+	}
+	req.ContentLength = info.Size()
+	req.URL.RawQuery = r.URL.RawQuery
+	copyHeader(req.Header, r.Header)
+	f.rewriteBuildID(req, f.upstreamID)
+
+	client := f.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return upstreamResponse{err: err}
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	return upstreamResponse{header: resp.Header, code: resp.StatusCode, body: data, err: err}
+}
+
+func (f *Forwarder) keepFailed(r *http.Request, body io.ReaderAt) {
+	if f.FailedDir == "" {
+		return
+	}
+	_, err := submission.Create(f.FailedDir, r.URL.Query(), io.NewSectionReader(body, 0, 1<<62))
+	if err != nil {
+		log.Printf("forward to %s: %v", f.Upstream, err)
+	}
+}
+
+func (f *Forwarder) remember(local, upstream string) {
+	if local == "" || upstream == "" {
+		return
+	}
+	f.toLocal.Store(upstream, local)
+	f.toUpstream.Store(local, upstream)
+}
+
+// The client knows the build ID of the primary server only.
+func (f *Forwarder) localID(id string) string {
+	if !f.Primary {
+		return id
+	}
+	return lookup(&f.toLocal, id)
+}
+
+func (f *Forwarder) upstreamID(id string) string {
+	if f.Primary {
+		return id
+	}
+	return lookup(&f.toUpstream, id)
+}
+
+func lookup(m *expiring[string], id string) string {
+	if mapped, found := m.Load(id); found {
+		return mapped
+	}
+	return id
+}
+
+func (f *Forwarder) rewriteBuildID(r *http.Request, mapID func(string) string) {
+	query := r.URL.Query()
+	if id := query.Get("buildid"); id != "" {
+		query.Set("buildid", mapID(id))
+		r.URL.RawQuery = query.Encode()
+	}
+}
+
+// localFiles replaces which files the upstream server already has with what
+// the local server has. Otherwise, a file that only the upstream server has
+// would never be uploaded to the local server. The upstream server receives
+// such files again.
+func localFiles(upstream, local []byte) []byte {
+	var up, loc map[string]json.RawMessage
+	if json.Unmarshal(upstream, &up) != nil || json.Unmarshal(local, &loc) != nil {
+		return upstream
+	}
+	if up["datafilesmd5"] == nil || loc["datafilesmd5"] == nil {
+		return upstream
+	}
+
+	up["datafilesmd5"] = loc["datafilesmd5"]
+	data, err := json.Marshal(up)
+	if err != nil {
+		return upstream
+	}
+	return data
+}
+
+// buildID extracts the buildid from the JSON response to a POST request.
+func buildID(body []byte) string {
+	var resp struct {
+		BuildID json.RawMessage `json:"buildid"`
+	}
+	if json.Unmarshal(body, &resp) != nil || resp.BuildID == nil {
+		return ""
+	}
+
+	// CDash answers with a number, cdash-proxy with a string.
+	var s string
+	if json.Unmarshal(resp.BuildID, &s) == nil {
+		return s
+	}
+	return string(resp.BuildID)
+}
+
+type upstreamError struct {
+	code int
+	body []byte
+}
+
+func (e *upstreamError) Error() string {
+	return http.StatusText(e.code) + ": " + string(bytes.TrimSpace(e.body))
+}
+
+// responseBuffer captures the local response while the upstream response is
+// awaited.
+type responseBuffer struct {
+	header http.Header
+	code   int
+	body   bytes.Buffer
+}
+
+func (b *responseBuffer) Header() http.Header {
+	return b.header
+}
+
+func (b *responseBuffer) Write(data []byte) (int, error) {
+	return b.body.Write(data)
+}
+
+func (b *responseBuffer) WriteHeader(code int) {
+	b.code = code
+}
+
+func copyHeader(dst, src http.Header) {
+	for k, v := range src {
+		if k == "Content-Length" {
+			continue
+		}
+		dst[k] = v
+	}
+}
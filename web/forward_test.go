@@ -0,0 +1,127 @@
+// SPDX-FileCopyrightText: 2025 Daniel Pfeifer <daniel@pfeifer-mail.de>
+// SPDX-License-Identifier: ISC
+
+package web
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/chorse-dev/cdash-proxy/submission"
+)
+
+// fakeCDash answers like CDash's submit.php and records the uploads.
+type fakeCDash struct {
+	uploads []string
+}
+
+func (c *fakeCDash) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	c.uploads = append(c.uploads, r.Method+" "+r.URL.RawQuery+" "+fmt.Sprint(len(body)))
+	if r.Method == http.MethodPost {
+		fmt.Fprint(w, `{"status":0,"datafilesmd5":[0],"buildid":42}`)
+		return
+	}
+	fmt.Fprint(w, `<cdash><status>OK</status><buildId>42</buildId></cdash>`)
+}
+
+func putConfigure(t *testing.T, h http.Handler) string {
+	file, err := os.Open("../ctestxml/testdata/Configure.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodPut, "/submit.php?project=Example&FileName=Configure.xml", file))
+	return w.Body.String()
+}
+
+func TestForwardSecondary(t *testing.T) {
+	cdash := &fakeCDash{}
+	upstream := httptest.NewServer(cdash)
+	defer upstream.Close()
+
+	f := &Forwarder{Upstream: upstream.URL + "/submit.php"}
+	body := putConfigure(t, f.Handler(serveOK))
+
+	if !strings.Contains(body, "<buildId>4e5a4b59fc4badd8ec47227aa4514ba1</buildId>") {
+		t.Errorf("expected local response, got %s", body)
+	}
+	if len(cdash.uploads) != 1 || !strings.HasPrefix(cdash.uploads[0], "PUT project=Example&FileName=Configure.xml ") {
+		t.Errorf("unexpected uploads %v", cdash.uploads)
+	}
+}
+
+func TestForwardPrimary(t *testing.T) {
+	cdash := &fakeCDash{}
+	upstream := httptest.NewServer(cdash)
+	defer upstream.Close()
+
+	f := &Forwarder{Upstream: upstream.URL + "/submit.php", Primary: true}
+	body := putConfigure(t, f.Handler(serveOK))
+
+	if !strings.Contains(body, "<buildId>42</buildId>") {
+		t.Errorf("expected upstream response, got %s", body)
+	}
+
+	// The client passes the upstream build ID, the local handler receives its own.
+	var localBuildID string
+	h := f.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost {
+			Post(w, r)
+			return
+		}
+		localBuildID = r.URL.Query().Get("buildid")
+	}))
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/submit.php?project=Example&site=s&stamp=t&build=b", nil))
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodPut, "/submit.php?type=GcovTar&buildid=42", strings.NewReader("")))
+
+	if localBuildID == "" || localBuildID == "42" {
+		t.Errorf("expected local build ID, got %q", localBuildID)
+	}
+}
+
+func TestForwardFailure(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "down for maintenance", http.StatusServiceUnavailable)
+	}))
+	defer upstream.Close()
+
+	dir := t.TempDir()
+	f := &Forwarder{Upstream: upstream.URL, Primary: true, FailedDir: dir}
+	body := putConfigure(t, f.Handler(serveOK))
+
+	if !strings.Contains(body, "<status>OK</status>") {
+		t.Errorf("expected local response, got %s", body)
+	}
+	if subs, _ := submission.List(dir); len(subs) != 1 {
+		t.Errorf("expected failed submission to be kept")
+	}
+}
+
+func TestForwardPrimaryFiles(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"status":0,"datafilesmd5":[1],"buildid":42}`)
+	}))
+	defer upstream.Close()
+
+	f := &Forwarder{Upstream: upstream.URL + "/submit.php", Primary: true}
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodPost, "/submit.php?project=Example&site=s&stamp=t&build=b&datafilesmd5[0]=abc", nil)
+	f.Handler(http.HandlerFunc(Post)).ServeHTTP(w, r)
+
+	// The local server does not have the file, so the client must upload it.
+	body := w.Body.String()
+	if !strings.Contains(body, `"datafilesmd5":[0]`) || !strings.Contains(body, `"buildid":42`) {
+		t.Errorf("unexpected response %s", body)
+	}
+}
@@ -0,0 +1,81 @@
+// SPDX-FileCopyrightText: 2025 Daniel Pfeifer <daniel@pfeifer-mail.de>
+// SPDX-License-Identifier: ISC
+
+package web
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/chorse-dev/cdash-proxy/profile"
+)
+
+func TestReady(t *testing.T) {
+	var failing error
+	h := &Health{Checks: []func(context.Context) error{
+		func(context.Context) error { return failing },
+	}}
+
+	for _, tc := range []struct {
+		ready bool
+		err   error
+		code  int
+	}{
+		{false, nil, http.StatusServiceUnavailable},
+		{true, nil, http.StatusOK},
+		{true, errors.New("database is locked"), http.StatusServiceUnavailable},
+	} {
+		h.SetReady(tc.ready)
+		failing = tc.err
+		w := httptest.NewRecorder()
+		h.Ready(w, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+		if w.Code != tc.code {
+			t.Errorf("ready=%v err=%v: got %d, want %d", tc.ready, tc.err, w.Code, tc.code)
+		}
+	}
+}
+
+func TestUploadKind(t *testing.T) {
+	for query, want := range map[string]string{
+		"FileName=site___build___20250101-0000-Experimental___XML___Configure.xml":            "Configure",
+		"FileName=site___build___20250101-0000-Experimental___XML___CoverageLog-0.xml":        "Coverage",
+		"FileName=site___build___20250101-0000-Experimental___XML___DynamicAnalysis-Test.xml": "DynamicAnalysis",
+		"FileName=Test.xml":               "Test",
+		"FileName=Random-1.xml":           "other",
+		"FileName=gcov.tbz2&type=GcovTar": "GcovTar",
+	} {
+		q, _ := url.ParseQuery(query)
+		if got := uploadKind(q); got != want {
+			t.Errorf("%s: got %q, want %q", query, got, want)
+		}
+	}
+}
+
+func TestTestStatusLabel(t *testing.T) {
+	for status, want := range map[string]string{
+		"passed": "passed",
+		"notrun": "notrun",
+		"random": "other",
+	} {
+		if got := testStatusLabel(status); got != want {
+			t.Errorf("%q: got %q, want %q", status, got, want)
+		}
+	}
+}
+
+func TestProjectLabel(t *testing.T) {
+	profiles := profile.Registry{"Example": &profile.Profile{}}
+	for project, want := range map[string]string{
+		"Example": "Example",
+		"Random":  "other",
+		"":        "other",
+	} {
+		if got := projectLabel(project, profiles); got != want {
+			t.Errorf("%q: got %q, want %q", project, got, want)
+		}
+	}
+}
@@ -0,0 +1,122 @@
+// SPDX-FileCopyrightText: 2025 Daniel Pfeifer <daniel@pfeifer-mail.de>
+// SPDX-License-Identifier: ISC
+
+package web
+
+import (
+	"io"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/chorse-dev/cdash-proxy/metrics"
+	"github.com/chorse-dev/cdash-proxy/model"
+	"github.com/chorse-dev/cdash-proxy/profile"
+)
+
+var (
+	submissionsTotal = metrics.NewCounter(metrics.Default, "cdash_proxy_submissions_total",
+		"Number of processed uploads.", "project", "kind", "result")
+	submissionBytes = metrics.NewHistogram(metrics.Default, "cdash_proxy_submission_bytes",
+		"Size of uploads in bytes.", metrics.SizeBuckets, "project", "kind")
+	parseSeconds = metrics.NewHistogram(metrics.Default, "cdash_proxy_parse_seconds",
+		"Time spent parsing uploads.", metrics.DurationBuckets, "project", "kind")
+	handlerSeconds = metrics.NewHistogram(metrics.Default, "cdash_proxy_handler_seconds",
+		"Time spent passing parsed jobs on.", metrics.DurationBuckets, "project", "kind")
+	diagnosticsTotal = metrics.NewCounter(metrics.Default, "cdash_proxy_diagnostics_total",
+		"Number of extracted diagnostics.", "project", "kind", "type")
+	testsTotal = metrics.NewCounter(metrics.Default, "cdash_proxy_tests_total",
+		"Number of extracted tests.", "project", "status")
+)
+
+// otherLabel is the label value of projects that are not configured, and of
+// upload kinds and test statuses that are not known. These values are chosen
+// by the client, so they must not add label values without bound.
+const otherLabel = "other"
+
+func projectLabel(project string, profiles profile.Registry) string {
+	if _, found := profiles[project]; found {
+		return project
+	}
+	return otherLabel
+}
+
+// knownKinds are the parts that CTest submits.
+var knownKinds = map[string]bool{
+	"Update":          true,
+	"Configure":       true,
+	"Build":           true,
+	"Test":            true,
+	"Coverage":        true,
+	"DynamicAnalysis": true,
+	"Notes":           true,
+	"Upload":          true,
+	"Done":            true,
+}
+
+var reFileNumber = regexp.MustCompile(`-[0-9]+$`)
+
+// uploadKind derives the kind of an upload from its query parameters. CTest
+// prefixes the XML file names with site, build name and stamp, separated by
+// "___", and numbers the CoverageLog files.
+func uploadKind(query url.Values) string {
+	if isGcovTar(query) {
+		return "GcovTar"
+	}
+
+	if !isCTestXML(query) {
+		return "Unknown"
+	}
+
+	name := query.Get("FileName")
+	if i := strings.LastIndex(name, "___"); i != -1 {
+		name = name[i+3:]
+	}
+	name = reFileNumber.ReplaceAllString(strings.TrimSuffix(name, ".xml"), "")
+
+	switch name {
+	case "CoverageLog":
+		return "Coverage"
+	case "DynamicAnalysis-Test":
+		return "DynamicAnalysis"
+	}
+	if !knownKinds[name] {
+		return otherLabel
+	}
+	return name
+}
+
+func testStatusLabel(status string) string {
+	switch status {
+	case "passed", "failed", "notrun":
+		return status
+	}
+	return otherLabel
+}
+
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
+
+func observeJob(project, kind string, job *model.Job) {
+	for _, cmd := range job.Commands {
+		for _, diag := range cmd.Diagnostics {
+			diagnosticsTotal.Inc(project, kind, diag.Type)
+		}
+		if cmd.Role == "test" {
+			testsTotal.Inc(project, testStatusLabel(cmd.TestStatus))
+		}
+	}
+}
+
+func since(start time.Time) float64 {
+	return time.Since(start).Seconds()
+}
@@ -5,20 +5,48 @@ package web
 
 import (
 	"encoding/json"
+	"log"
 	"net/http"
+	"net/url"
 
 	"github.com/chorse-dev/cdash-proxy/ctestxml"
 )
 
+// Post answers the first step of CTest's two-step upload protocol without
+// keeping any files, so CTest always uploads the file.
 func Post(w http.ResponseWriter, r *http.Request) {
-	query := r.URL.Query()
+	(&Server{}).post(w, r)
+}
+
+// The first step announces the file by its MD5 checksum. If the file is
+// already known, CTest skips the second step and the stored file is processed
+// for the announced job instead.
+func (s *Server) post(w http.ResponseWriter, r *http.Request) {
 	jobID := ctestxml.GenerateJobID(
-		query.Get("project"),
-		query.Get("site"),
-		query.Get("stamp"),
-		query.Get("build"),
+		r.FormValue("project"),
+		r.FormValue("site"),
+		r.FormValue("stamp"),
+		r.FormValue("build"),
 	)
 
+	s.builds.Store(jobID, build{r.FormValue("project"), r.FormValue("site")})
+
+	present := 0
+	if sum := r.FormValue("datafilesmd5[0]"); s.Blobs != nil && s.Blobs.Has(sum) {
+		query := url.Values{
+			"project":  {r.FormValue("project")},
+			"type":     {r.FormValue("type")},
+			"filename": {r.FormValue("filename")},
+			"md5":      {sum},
+			"buildid":  {jobID},
+		}
+		if err := s.reuse(r, query); err != nil {
+			log.Printf("reuse %s: %v", sum, err)
+		} else {
+			present = 1
+		}
+	}
+
 	type uploadRequest struct {
 		Status       int    `json:"status"`
 		DataFilesMD5 []int  `json:"datafilesmd5"`
@@ -27,7 +55,28 @@ func Post(w http.ResponseWriter, r *http.Request) {
 
 	json.NewEncoder(w).Encode(uploadRequest{
 		Status:       0,
-		DataFilesMD5: []int{0},
+		DataFilesMD5: []int{present},
 		JobID:        jobID,
 	})
 }
+
+// reuse processes a stored file as if it was uploaded with the given query.
+func (s *Server) reuse(r *http.Request, query url.Values) error {
+	if !isGcovTar(query) {
+		return nil
+	}
+
+	body, err := s.Blobs.Open(query.Get("md5"))
+	if err != nil {
+		return err
+	}
+	defer body.Close()
+
+	if s.Spool != nil {
+		_, err = s.Spool.Put(r.Context(), query, body)
+		return err
+	}
+
+	_, err = handle(r.Context(), query, body, s.Handler, s.Profiles)
+	return err
+}
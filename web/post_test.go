@@ -4,10 +4,21 @@
 package web
 
 import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"encoding/hex"
 	"io"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
+	"os"
+	"strings"
 	"testing"
+
+	"github.com/chorse-dev/cdash-proxy/blob"
+	"github.com/chorse-dev/cdash-proxy/ctestxml"
+	"github.com/chorse-dev/cdash-proxy/model"
 )
 
 func TestPost(t *testing.T) {
@@ -21,3 +32,63 @@ func TestPost(t *testing.T) {
 		t.Errorf("expected error to be nil got %v", err)
 	}
 }
+
+func TestUploadHandshake(t *testing.T) {
+	data, err := os.ReadFile("../gcovtar/testdata/gcov.tbz2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sum := md5.Sum(data)
+	md5sum := hex.EncodeToString(sum[:])
+
+	store, err := blob.NewStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var jobs []string
+	s := &Server{Blobs: store, Handler: func(ctx context.Context, job *model.Job) error {
+		jobs = append(jobs, job.JobID)
+		return nil
+	}}
+
+	post := func(build string) string {
+		form := url.Values{
+			"project":         {"Example"},
+			"site":            {"site"},
+			"stamp":           {"20250101-0000-Experimental"},
+			"build":           {build},
+			"type":            {"GcovTar"},
+			"datafilesmd5[0]": {md5sum},
+		}
+		r := httptest.NewRequest(http.MethodPost, "/submit.php", strings.NewReader(form.Encode()))
+		r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, r)
+		return w.Body.String()
+	}
+
+	put := func(sum string) string {
+		r := httptest.NewRequest(http.MethodPut, "/submit.php?type=GcovTar&buildid=1&md5="+sum, bytes.NewReader(data))
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, r)
+		return w.Body.String()
+	}
+
+	if body := post("first"); !strings.Contains(body, `"datafilesmd5":[0]`) {
+		t.Errorf("expected unknown file, got %s", body)
+	}
+	if body := put(strings.Repeat("0", 32)); !strings.Contains(body, "MD5 mismatch") {
+		t.Errorf("expected checksum error, got %s", body)
+	}
+	if body := put(md5sum); body != `{"status":0}` {
+		t.Errorf("unexpected body %s", body)
+	}
+	if body := post("second"); !strings.Contains(body, `"datafilesmd5":[1]`) {
+		t.Errorf("expected known file, got %s", body)
+	}
+
+	if len(jobs) != 2 || jobs[1] != ctestxml.GenerateJobID("Example", "site", "20250101-0000-Experimental", "second") {
+		t.Errorf("expected stored file to be processed for second job, got %v", jobs)
+	}
+}
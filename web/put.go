@@ -4,35 +4,41 @@
 package web
 
 import (
+	"context"
 	"encoding/xml"
+	"errors"
 	"fmt"
+	"io"
 	"log"
 	"net/http"
+	"net/url"
 	"path/filepath"
+	"time"
 
 	"github.com/chorse-dev/cdash-proxy/ctestxml"
 	"github.com/chorse-dev/cdash-proxy/gcovtar"
+	"github.com/chorse-dev/cdash-proxy/model"
+	"github.com/chorse-dev/cdash-proxy/profile"
+	"github.com/chorse-dev/cdash-proxy/spool"
+	"github.com/chorse-dev/cdash-proxy/submission"
 )
 
+var errUnknownUpload = errors.New("unknown upload")
+
 func Put(w http.ResponseWriter, r *http.Request, hf HandlerFunc) {
-	if r.FormValue("type") == "GcovTar" {
-		buildID := r.FormValue("buildid")
-		job, err := gcovtar.Parse(r.Body, buildID)
-		if err == nil {
-			err = hf(r.Context(), job)
-		}
-		if err != nil {
-			fmt.Printf("ERROR: %s\n", err.Error())
-			fmt.Fprintf(w, `{"error":"%s"}`, err.Error())
-			return
-		}
-		fmt.Fprintf(w, `{"status":0}`)
+	put(w, r, hf, nil)
+}
+
+func put(w http.ResponseWriter, r *http.Request, hf HandlerFunc, profiles profile.Registry) {
+	query := r.URL.Query()
+	if isGcovTar(query) {
+		_, err := handle(r.Context(), query, r.Body, hf, profiles)
+		sendResponseJSON(w, err)
 		return
 	}
 
-	if filepath.Ext(r.FormValue("FileName")) == ".xml" {
-		buildID, err := handleCTestXML(r, hf)
-
+	if isCTestXML(query) {
+		buildID, err := handle(r.Context(), query, r.Body, hf, profiles)
 		sendResponseXML(w, buildID, err)
 		return
 	}
@@ -40,19 +46,142 @@ func Put(w http.ResponseWriter, r *http.Request, hf HandlerFunc) {
 	http.NotFound(w, r)
 }
 
-func handleCTestXML(r *http.Request, hf HandlerFunc) (string, error) {
-	job, err := ctestxml.Parse(r.Body, r.FormValue("project"))
+// putSpool stores the body in the spool and answers immediately. The job ID
+// of XML files is determined from the spooled file without parsing it.
+func putSpool(w http.ResponseWriter, r *http.Request, sp *spool.Spool) {
+	query := r.URL.Query()
+	if !isGcovTar(query) && !isCTestXML(query) {
+		http.NotFound(w, r)
+		return
+	}
+
+	sub, err := sp.Put(r.Context(), query, r.Body)
+	if isGcovTar(query) {
+		sendResponseJSON(w, err)
+		return
+	}
+
+	var buildID string
+	if err == nil {
+		buildID, err = peekJobID(sub)
+	}
+	sendResponseXML(w, buildID, err)
+}
+
+// JobID returns the ID of the job that a spooled submission belongs to, or
+// an empty string if it cannot be determined.
+func JobID(sub *submission.Submission) string {
+	if isGcovTar(sub.Query) {
+		return sub.Query.Get("buildid")
+	}
+	if !isCTestXML(sub.Query) {
+		return ""
+	}
+	id, _ := peekJobID(sub)
+	return id
+}
+
+func peekJobID(sub *submission.Submission) (string, error) {
+	body, err := sub.Open()
 	if err != nil {
 		return "", err
 	}
+	defer body.Close()
 
-	if err := hf(r.Context(), job); err != nil {
+	return ctestxml.PeekJobID(body, sub.Query.Get("project"))
+}
+
+// Process parses spooled submissions with the profiles of their projects and
+// passes the resulting jobs to hf.
+func Process(hf HandlerFunc, profiles profile.Registry) spool.ProcessFunc {
+	return func(ctx context.Context, sub *submission.Submission) error {
+		body, err := sub.Open()
+		if err != nil {
+			return err
+		}
+		defer body.Close()
+
+		_, err = handle(ctx, sub.Query, body, hf, profiles)
+		return err
+	}
+}
+
+// Replay parses archived submissions again and passes the resulting jobs,
+// marked as reprocessed, to hf.
+func Replay(hf HandlerFunc, profiles profile.Registry) spool.ProcessFunc {
+	return Process(func(ctx context.Context, job *model.Job) error {
+		job.Reprocessed = true
+		return hf(ctx, job)
+	}, profiles)
+}
+
+func isGcovTar(query url.Values) bool {
+	return query.Get("type") == "GcovTar"
+}
+
+func isCTestXML(query url.Values) bool {
+	return filepath.Ext(query.Get("FileName")) == ".xml"
+}
+
+// handle parses the body according to the query parameters of the upload and
+// returns the ID of the job.
+func handle(ctx context.Context, query url.Values, body io.Reader, hf HandlerFunc, profiles profile.Registry) (string, error) {
+	project := query.Get("project")
+	p := profiles.Lookup(project)
+	kind := uploadKind(query)
+	label := projectLabel(project, profiles)
+	counter := &countingReader{r: body}
+
+	start := time.Now()
+	var job *model.Job
+	var err error
+	switch {
+	case isGcovTar(query):
+		job, err = gcovtar.ParseProfile(counter, query.Get("buildid"), p)
+	case isCTestXML(query):
+		job, err = ctestxml.ParseProfile(counter, project, p)
+	default:
+		err = errUnknownUpload
+	}
+	parseSeconds.Observe(since(start), label, kind)
+	submissionBytes.Observe(float64(counter.n), label, kind)
+	if err != nil {
+		submissionsTotal.Inc(label, kind, "parse_error")
+		return "", err
+	}
+	observeJob(label, kind, job)
+
+	start = time.Now()
+	job.ParserVersion = model.ParserVersion
+	err = hf(ctx, job)
+	handlerSeconds.Observe(since(start), label, kind)
+	if err != nil {
+		submissionsTotal.Inc(label, kind, "handler_error")
 		return "", err
 	}
 
+	submissionsTotal.Inc(label, kind, "ok")
 	return job.JobID, nil
 }
 
+// sendResponse answers in the format that CTest expects for the upload.
+func sendResponse(w http.ResponseWriter, query url.Values, buildID string, err error) {
+	if isCTestXML(query) {
+		sendResponseXML(w, buildID, err)
+		return
+	}
+	sendResponseJSON(w, err)
+}
+
+func sendResponseJSON(w http.ResponseWriter, err error) {
+	if err != nil {
+		fmt.Printf("ERROR: %s\n", err.Error())
+		fmt.Fprintf(w, `{"error":"%s"}`, err.Error())
+		return
+	}
+	fmt.Fprintf(w, `{"status":0}`)
+}
+
 func sendResponseXML(w http.ResponseWriter, buildID string, err error) {
 	var resp *ctestxml.Response
 	if err == nil {
@@ -62,7 +191,7 @@ func sendResponseXML(w http.ResponseWriter, buildID string, err error) {
 		}
 	} else {
 		log.Println(err)
-		w.WriteHeader(http.StatusInternalServerError)
+		w.WriteHeader(errorStatus(err))
 		resp = &ctestxml.Response{
 			Status:  "ERROR",
 			Message: err.Error(),
@@ -72,3 +201,12 @@ func sendResponseXML(w http.ResponseWriter, buildID string, err error) {
 		panic(err)
 	}
 }
+
+// errorStatus returns the HTTP status code for a failed upload.
+func errorStatus(err error) int {
+	var mbe *http.MaxBytesError
+	if errors.As(err, &mbe) {
+		return http.StatusRequestEntityTooLarge
+	}
+	return http.StatusInternalServerError
+}
@@ -10,9 +10,12 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"os"
+	"strings"
 	"testing"
 
 	"github.com/chorse-dev/cdash-proxy/model"
+	"github.com/chorse-dev/cdash-proxy/spool"
+	"github.com/chorse-dev/cdash-proxy/submission"
 )
 
 var serveOK = Serve(func(ctx context.Context, job *model.Job) error {
@@ -86,3 +89,126 @@ func TestPutXmlError(t *testing.T) {
 		t.Errorf("unexpected body: %s", string(body))
 	}
 }
+
+func TestPutSpool(t *testing.T) {
+	jobs := make(chan *model.Job, 1)
+	sp := spool.New(t.TempDir(), Process(func(ctx context.Context, job *model.Job) error {
+		jobs <- job
+		return nil
+	}, nil))
+	if err := sp.Start(); err != nil {
+		t.Fatal(err)
+	}
+	s := &Server{Spool: sp}
+
+	file, _ := os.Open("../ctestxml/testdata/Configure.xml")
+	defer file.Close()
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodPut, "/submit?project=Example&FileName=Configure.xml", file)
+	s.ServeHTTP(w, r)
+	if body := w.Body.String(); body != `<cdash><status>OK</status><buildId>4e5a4b59fc4badd8ec47227aa4514ba1</buildId></cdash>` {
+		t.Errorf("unexpected body: %s", body)
+	}
+
+	if err := sp.Close(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if job := <-jobs; job.JobID != "4e5a4b59fc4badd8ec47227aa4514ba1" {
+		t.Errorf("unexpected job %s", job.JobID)
+	}
+}
+
+func TestArchiveReplay(t *testing.T) {
+	dir := t.TempDir()
+	s := &Server{Handler: func(ctx context.Context, job *model.Job) error {
+		if job.ParserVersion != model.ParserVersion || job.Reprocessed {
+			t.Errorf("unexpected job %+v", job)
+		}
+		return nil
+	}, Archive: dir}
+
+	file, _ := os.Open("../ctestxml/testdata/Configure.xml")
+	defer file.Close()
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, httptest.NewRequest(http.MethodPut, "/submit?project=Example&FileName=Configure.xml", file))
+	if w.Code != http.StatusOK {
+		t.Fatalf("unexpected status %d", w.Code)
+	}
+
+	subs, err := submission.List(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(subs) != 1 || subs[0].Query.Get("FileName") != "Configure.xml" {
+		t.Fatalf("expected one archived upload, got %+v", subs)
+	}
+
+	var reprocessed *model.Job
+	err = Replay(func(ctx context.Context, job *model.Job) error {
+		reprocessed = job
+		return nil
+	}, nil)(context.Background(), subs[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reprocessed == nil || !reprocessed.Reprocessed {
+		t.Error("expected reprocessed job")
+	}
+}
+
+func TestPutBodyLimit(t *testing.T) {
+	s := &Server{
+		Handler: func(ctx context.Context, job *model.Job) error { return nil },
+		BodyLimit: func(project string) int64 {
+			if project == "Small" {
+				return 100
+			}
+			return 0
+		},
+	}
+
+	for project, code := range map[string]int{
+		"Small": http.StatusRequestEntityTooLarge,
+		"Large": http.StatusOK,
+	} {
+		file, _ := os.Open("../ctestxml/testdata/Build.xml")
+		defer file.Close()
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodPut, "/submit?project="+project+"&FileName=Build.xml", file)
+		s.ServeHTTP(w, r)
+		if w.Code != code {
+			t.Errorf("%s: got %d, want %d", project, w.Code, code)
+		}
+	}
+}
+
+func TestPutBodyLimitAnnounced(t *testing.T) {
+	cdash := &fakeCDash{}
+	upstream := httptest.NewServer(cdash)
+	defer upstream.Close()
+
+	s := &Server{
+		Handler: func(ctx context.Context, job *model.Job) error { return nil },
+		BodyLimit: func(project string) int64 {
+			if project == "Small" {
+				return 10
+			}
+			return 0
+		},
+		Forwarder: &Forwarder{Upstream: upstream.URL + "/submit.php"},
+	}
+
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/submit.php?project=Small&site=s&stamp=t&build=b", nil))
+	jobID := buildID(w.Body.Bytes())
+
+	w = httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodPut, "/submit.php?type=GcovTar&buildid="+jobID, strings.NewReader(strings.Repeat("x", 100)))
+	s.ServeHTTP(w, r)
+	if w.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("got %d, want %d", w.Code, http.StatusRequestEntityTooLarge)
+	}
+	if len(cdash.uploads) != 1 {
+		t.Errorf("expected only the announcement to be forwarded, got %v", cdash.uploads)
+	}
+}
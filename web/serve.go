@@ -5,23 +5,205 @@ package web
 
 import (
 	"context"
+	"errors"
+	"io"
 	"net/http"
+	"net/url"
+	"os"
 
+	"github.com/chorse-dev/cdash-proxy/auth"
+	"github.com/chorse-dev/cdash-proxy/blob"
 	"github.com/chorse-dev/cdash-proxy/model"
+	"github.com/chorse-dev/cdash-proxy/profile"
+	"github.com/chorse-dev/cdash-proxy/spool"
+	"github.com/chorse-dev/cdash-proxy/submission"
 )
 
 type HandlerFunc func(ctx context.Context, job *model.Job) error
 
+// Server answers the submissions of CTest.
+type Server struct {
+	// Handler receives the parsed jobs.
+	Handler HandlerFunc
+
+	// Spool, if set, receives the uploads instead of Handler. The spool is
+	// expected to be processed with Process(Handler, Profiles).
+	Spool *spool.Spool
+
+	// Archive, if set, is a directory where every raw upload is kept, so
+	// it can be reprocessed with Replay.
+	Archive string
+
+	// Blobs, if set, keeps the files of CTest's two-step upload protocol,
+	// so files that were uploaded before need not be uploaded again.
+	Blobs *blob.Store
+
+	// Auth, if set, decides which submissions are accepted.
+	Auth *auth.Tokens
+
+	// Profiles holds the processing settings of the projects.
+	Profiles profile.Registry
+
+	// BodyLimit, if set, returns the maximum size of uploads for a
+	// project; zero means no limit.
+	BodyLimit func(project string) int64
+
+	// Forwarder, if set, relays the submissions that passed the body limit
+	// and authorization to an upstream server.
+	Forwarder *Forwarder
+
+	// Maps build IDs to the project and site announced in the first step
+	// of the upload protocol. Uploads of the second step carry no project.
+	builds expiring[build]
+}
+
 func Serve(hf HandlerFunc) http.HandlerFunc {
-	return func(w http.ResponseWriter, r *http.Request) {
-		switch r.Method {
-		case "PUT":
-			Put(w, r, hf)
-		case "POST":
-			Post(w, r)
-		default:
-			http.Error(w, http.StatusText(http.StatusMethodNotAllowed),
-				http.StatusMethodNotAllowed)
+	return (&Server{Handler: hf}).ServeHTTP
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if s.BodyLimit != nil && r.Method == "PUT" {
+		if limit := s.BodyLimit(s.project(r.URL.Query())); limit > 0 {
+			if r.ContentLength > limit {
+				http.Error(w, http.StatusText(http.StatusRequestEntityTooLarge),
+					http.StatusRequestEntityTooLarge)
+				return
+			}
+			r.Body = http.MaxBytesReader(w, r.Body, limit)
+		}
+	}
+
+	if s.Auth != nil && (r.Method == "PUT" || r.Method == "POST") {
+		body := r.Body
+		err := s.authorize(r)
+		if r.Body != body {
+			// The body was replaced by a temporary file.
+			defer r.Body.Close()
+		}
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			http.Error(w, http.StatusText(http.StatusRequestEntityTooLarge),
+				http.StatusRequestEntityTooLarge)
+			return
+		}
+		if err != nil {
+			sendAuthError(w, err)
+			return
+		}
+	}
+
+	if s.Forwarder != nil {
+		s.Forwarder.Handler(http.HandlerFunc(s.dispatch)).ServeHTTP(w, r)
+		return
+	}
+	s.dispatch(w, r)
+}
+
+func (s *Server) dispatch(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case "PUT":
+		s.put(w, r)
+	case "POST":
+		s.post(w, r)
+	default:
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed),
+			http.StatusMethodNotAllowed)
+	}
+}
+
+// project returns the project of an upload, which uploads of the second step
+// of the upload protocol only carry through their build ID.
+func (s *Server) project(query url.Values) string {
+	if project := query.Get("project"); project != "" {
+		return project
+	}
+	b, _ := s.lookupBuild(query.Get("buildid"))
+	return b.project
+}
+
+// lookupBuild returns the project and site announced for a build ID. With a
+// primary upstream server, the client knows the upstream build ID only.
+func (s *Server) lookupBuild(id string) (build, bool) {
+	if s.Forwarder != nil {
+		id = s.Forwarder.localID(id)
+	}
+	return s.builds.Load(id)
+}
+
+func (s *Server) put(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	if query.Get("project") == "" {
+		if b, found := s.builds.Load(query.Get("buildid")); found {
+			query.Set("project", b.project)
+			r.URL.RawQuery = query.Encode()
+		}
+	}
+
+	if s.Archive != "" {
+		body, err := archive(r, s.Archive)
+		if err != nil {
+			http.Error(w, err.Error(), errorStatus(err))
+			return
+		}
+		defer body.Close()
+		r.Body = body
+	}
+
+	if sum := query.Get("md5"); sum != "" {
+		body, err := s.verify(r.Body, sum)
+		if err != nil {
+			sendResponse(w, query, "", err)
+			return
 		}
+		defer body.Close()
+		r.Body = body
+
+		// Files of other upload types are only kept in the store.
+		if !isGcovTar(query) && !isCTestXML(query) && s.Blobs != nil {
+			sendResponseJSON(w, nil)
+			return
+		}
+	}
+
+	if s.Spool != nil {
+		putSpool(w, r, s.Spool)
+	} else {
+		put(w, r, s.Handler, s.Profiles)
+	}
+}
+
+// verify checks the body against the MD5 checksum that was announced in the
+// POST request and returns the verified content.
+func (s *Server) verify(body io.Reader, sum string) (io.ReadCloser, error) {
+	if s.Blobs != nil {
+		if err := s.Blobs.Put(sum, body); err != nil {
+			return nil, err
+		}
+		return s.Blobs.Open(sum)
+	}
+
+	tmp, err := os.CreateTemp("", "cdash-proxy-*")
+	if err != nil {
+		return nil, err
+	}
+	os.Remove(tmp.Name())
+
+	if err := blob.Verify(tmp, body, sum); err != nil {
+		tmp.Close()
+		return nil, err
+	}
+	if _, err := tmp.Seek(0, io.SeekStart); err != nil {
+		tmp.Close()
+		return nil, err
+	}
+	return tmp, nil
+}
+
+// archive stores the body of the request and returns the stored file.
+func archive(r *http.Request, dir string) (*os.File, error) {
+	sub, err := submission.Create(dir, r.URL.Query(), r.Body)
+	if err != nil {
+		return nil, err
 	}
+	return sub.Open()
 }